@@ -3,23 +3,32 @@ package go_framework
 import (
 	"encoding/json"
 	"errors"
+	"github.com/JUYAFEI/go-framework/binding"
 	golog "github.com/JUYAFEI/go-framework/log"
 	"github.com/JUYAFEI/go-framework/render"
+	"github.com/JUYAFEI/go-framework/validator"
+	"github.com/JUYAFEI/go-framework/websocket"
 	"html/template"
 	"io"
 	"log"
+	"math"
 	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 )
 
 const DefaultMemory = 32 << 20
 
+// abortIndex 处理链被中止后 index 的值
+const abortIndex = math.MaxInt32 >> 1
+
 type Context struct {
-	W          http.ResponseWriter
+	writermem  responseWriter
+	W          ResponseWriter
 	R          *http.Request
 	Engine     *Engine
 	queryCache url.Values
@@ -29,6 +38,57 @@ type Context struct {
 	sameSite   http.SameSite
 	mu         sync.RWMutex
 	Keys       map[string]any
+	Params     Params
+	HostParams Params          // Router.Host 分组从 Host 中捕获的参数
+	WS         *websocket.Conn // RouterGroup.WebSocket 升级后的连接
+	handlers   []HandlerFunc
+	index      int
+}
+
+// reset 清理上一次请求留下的数据，Context 放回 Engine.pool 前调用
+func (c *Context) reset() {
+	c.W = nil
+	c.R = nil
+	c.queryCache = nil
+	c.formCache = nil
+	c.StatusCode = 0
+	c.sameSite = 0
+	c.Keys = nil
+	c.Params = c.Params[:0]
+	c.HostParams = c.HostParams[:0]
+	c.WS = nil
+	c.handlers = c.handlers[:0]
+	c.index = -1
+}
+
+// Next 执行处理链中剩余的 handler，只应在中间件中调用
+func (c *Context) Next() {
+	c.index++
+	for c.index < len(c.handlers) {
+		c.handlers[c.index](c)
+		c.index++
+	}
+}
+
+// Abort 中止处理链，当前 handler 返回后后面的 handler 不再执行
+func (c *Context) Abort() {
+	c.index = abortIndex
+}
+
+func (c *Context) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
+func (c *Context) AbortWithStatus(code int) {
+	c.Abort()
+	c.StatusCode = code
+	c.W.WriteHeader(code)
+	c.W.WriteHeaderNow()
+}
+
+func (c *Context) AbortWithStatusJSON(code int, data any) error {
+	c.Abort()
+	return c.JSON(code, data)
 }
 
 func (c *Context) SetSameSite(s http.SameSite) {
@@ -50,6 +110,36 @@ func (c *Context) Get(key string) (value any, ok bool) {
 	return
 }
 
+// Param 返回路由参数的值，如 /user/get/:id 中的 id，不存在时返回空字符串
+func (c *Context) Param(key string) string {
+	return c.Params.ByName(key)
+}
+
+// HostParam 返回 Host 参数的值，如 {tenant}.example.com 中的 tenant
+func (c *Context) HostParam(key string) string {
+	return c.HostParams.ByName(key)
+}
+
+func (c *Context) GetParam(key string) (string, bool) {
+	return c.Params.Get(key)
+}
+
+func (c *Context) ParamInt(key string) (int, error) {
+	return strconv.Atoi(c.Param(key))
+}
+
+func (c *Context) ParamInt64(key string) (int64, error) {
+	return strconv.ParseInt(c.Param(key), 10, 64)
+}
+
+func (c *Context) DefaultParamInt(key string, defaultValue int) int {
+	value, err := c.ParamInt(key)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
 func (c *Context) QueryMap(key string) (dicts map[string]string) {
 	dicts, _ = c.GetQueryMap(key)
 	return
@@ -84,6 +174,103 @@ func (c *Context) DealJson(data any) error {
 	return decoder.Decode(data)
 }
 
+// ShouldBind 根据请求方法和 Content-Type 选择 Binding，把请求数据绑定到 obj
+func (c *Context) ShouldBind(obj any) error {
+	return c.ShouldBindWith(obj, binding.Default(c.R.Method, c.R.Header.Get("Content-Type")))
+}
+
+func (c *Context) ShouldBindJSON(obj any) error {
+	return c.ShouldBindWith(obj, binding.JSON)
+}
+
+func (c *Context) ShouldBindXML(obj any) error {
+	return c.ShouldBindWith(obj, binding.XML)
+}
+
+func (c *Context) ShouldBindQuery(obj any) error {
+	return c.ShouldBindWith(obj, binding.Query)
+}
+
+func (c *Context) ShouldBindForm(obj any) error {
+	return c.ShouldBindWith(obj, binding.Form)
+}
+
+func (c *Context) ShouldBindMultipart(obj any) error {
+	return c.ShouldBindWith(obj, binding.FormMultipart)
+}
+
+func (c *Context) ShouldBindHeader(obj any) error {
+	return c.ShouldBindWith(obj, binding.Header)
+}
+
+// ShouldBindUri 按 uri tag 绑定路由参数
+func (c *Context) ShouldBindUri(obj any) error {
+	params := make(map[string][]string, len(c.Params))
+	for _, param := range c.Params {
+		params[param.Key] = append(params[param.Key], param.Value)
+	}
+	return binding.Uri.BindUri(params, obj)
+}
+
+func (c *Context) ShouldBindWith(obj any, b binding.Binding) error {
+	return b.Bind(c.R, obj)
+}
+
+// Bind 与 ShouldBind 相同，绑定失败时返回 400 并中止处理链
+func (c *Context) Bind(obj any) error {
+	return c.MustBindWith(obj, binding.Default(c.R.Method, c.R.Header.Get("Content-Type")))
+}
+
+func (c *Context) BindJSON(obj any) error {
+	return c.MustBindWith(obj, binding.JSON)
+}
+
+func (c *Context) BindXML(obj any) error {
+	return c.MustBindWith(obj, binding.XML)
+}
+
+func (c *Context) BindQuery(obj any) error {
+	return c.MustBindWith(obj, binding.Query)
+}
+
+func (c *Context) BindForm(obj any) error {
+	return c.MustBindWith(obj, binding.Form)
+}
+
+func (c *Context) BindMultipart(obj any) error {
+	return c.MustBindWith(obj, binding.FormMultipart)
+}
+
+func (c *Context) BindHeader(obj any) error {
+	return c.MustBindWith(obj, binding.Header)
+}
+
+func (c *Context) BindUri(obj any) error {
+	if err := c.ShouldBindUri(obj); err != nil {
+		c.bindFailed(err)
+		return err
+	}
+	return nil
+}
+
+// bindFailed 校验失败时返回 400 和每个字段的错误，其他错误只返回 400
+func (c *Context) bindFailed(err error) {
+	var errs validator.ValidationErrors
+	if errors.As(err, &errs) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, map[string]any{"errors": errs})
+		return
+	}
+	c.AbortWithStatus(http.StatusBadRequest)
+}
+
+func (c *Context) MustBindWith(obj any, b binding.Binding) error {
+	if err := c.ShouldBindWith(obj, b); err != nil {
+		c.bindFailed(err)
+		return err
+	}
+	return nil
+}
+
 func (c *Context) initQueryCache() {
 	if c.R != nil {
 		c.queryCache = c.R.URL.Query()
@@ -209,8 +396,10 @@ func (c *Context) HTMLTemplate(name string, data any) {
 	})
 }
 
+// HTMLTemplateGlob 加载模板并渲染，可以使用 Engine.FuncMap 中的函数，funcMap 中同名的函数优先
 func (c *Context) HTMLTemplateGlob(name string, funcMap template.FuncMap, pattern string, data any) {
 	t := template.New(name)
+	t.Funcs(c.Engine.FuncMap())
 	t.Funcs(funcMap)
 	t, err := t.ParseGlob(pattern)
 	if err != nil {
@@ -241,6 +430,33 @@ func (c *Context) XML(status int, data any) error {
 	return c.Render(status, render.XML{Data: data})
 }
 
+func (c *Context) YAML(status int, data any) error {
+	return c.Render(status, render.YAML{Data: data})
+}
+
+func (c *Context) TOML(status int, data any) error {
+	return c.Render(status, render.TOML{Data: data})
+}
+
+// ProtoBuf data 必须实现 proto.Message
+func (c *Context) ProtoBuf(status int, data any) error {
+	return c.Render(status, render.ProtoBuf{Data: data})
+}
+
+func (c *Context) MsgPack(status int, data any) error {
+	return c.Render(status, render.MsgPack{Data: data})
+}
+
+// CSV data 为结构体切片、[][]string 或 channel，见 render.CSV
+func (c *Context) CSV(status int, data any) error {
+	return c.Render(status, render.CSV{Data: data})
+}
+
+// CSVAttachment 以附件形式下载 CSV
+func (c *Context) CSVAttachment(filename string, data any) error {
+	return c.Render(http.StatusOK, render.CSV{Data: data, Filename: filename})
+}
+
 func (c *Context) File(filePath string) {
 	http.ServeFile(c.W, c.R, filePath)
 }
@@ -280,13 +496,89 @@ func (c *Context) String(status int, format string, values ...any) (err error) {
 	return
 }
 
+// Render 先设置状态码再写入响应体，不允许有响应体的状态码只写入响应头
 func (c *Context) Render(statusCode int, r render.Render) error {
-	err := r.Render(c.W)
 	c.StatusCode = statusCode
-	if statusCode != http.StatusOK {
-		c.W.WriteHeader(statusCode)
+	c.W.WriteHeader(statusCode)
+	if !bodyAllowedForStatus(statusCode) {
+		r.WriteContentType(c.W)
+		c.W.WriteHeaderNow()
+		return nil
 	}
-	return err
+	return r.Render(c.W)
+}
+
+func bodyAllowedForStatus(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent:
+		return false
+	case status == http.StatusNotModified:
+		return false
+	}
+	return true
+}
+
+// SSEvent 写入一个 Server-Sent Events 事件并立即 flush
+func (c *Context) SSEvent(name string, data any) error {
+	return c.WriteEvent(render.SSEvent{Event: name, Data: data})
+}
+
+// WriteEvent 写入带 id、retry 的事件并立即 flush
+func (c *Context) WriteEvent(event render.SSEvent) error {
+	if c.StatusCode == 0 {
+		c.StatusCode = http.StatusOK
+	}
+	if err := event.Render(c.W); err != nil {
+		return err
+	}
+	c.W.Flush()
+	return nil
+}
+
+// Stream 循环调用 step 并在每次调用后 flush，step 返回 false 时结束，
+// 客户端断开连接时返回 true，step 中阻塞等待数据时应同时监听 c.R.Context().Done()
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	done := c.R.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		default:
+			keepOpen := step(c.W)
+			c.W.Flush()
+			if !keepOpen {
+				return false
+			}
+		}
+	}
+}
+
+// LastEventID 客户端重连时携带的最后一个事件 id
+func (c *Context) LastEventID() string {
+	if id := c.R.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	return c.GetQuery("lastEventId")
+}
+
+// Protocol 请求使用的协议，如 HTTP/1.1、HTTP/2.0
+func (c *Context) Protocol() string {
+	return c.R.Proto
+}
+
+func (c *Context) IsHTTP2() bool {
+	return c.R.ProtoMajor == 2
+}
+
+// Push 服务端推送，ResponseWriter 不支持 http.Pusher 时返回 http.ErrNotSupported
+func (c *Context) Push(target string, opts *http.PushOptions) error {
+	pusher, ok := c.W.(http.Pusher)
+	if !ok {
+		return http.ErrNotSupported
+	}
+	return pusher.Push(target, opts)
 }
 
 func (c *Context) Fail(code int, msg string) {
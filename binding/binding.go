@@ -0,0 +1,65 @@
+package binding
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	MIMEJSON              = "application/json"
+	MIMEXML               = "application/xml"
+	MIMEXML2              = "text/xml"
+	MIMEPOSTForm          = "application/x-www-form-urlencoded"
+	MIMEMultipartPOSTForm = "multipart/form-data"
+)
+
+// defaultMemory 解析 multipart 表单时保存在内存中的最大字节数
+const defaultMemory = 32 << 20
+
+// Binding 从请求中读取数据写入结构体
+type Binding interface {
+	Name() string
+	Bind(req *http.Request, obj any) error
+}
+
+// BindingUri 从路由参数中读取数据写入结构体
+type BindingUri interface {
+	Name() string
+	BindUri(params map[string][]string, obj any) error
+}
+
+var (
+	JSON          = jsonBinding{}
+	XML           = xmlBinding{}
+	Form          = formBinding{}
+	Query         = queryBinding{}
+	FormPost      = formPostBinding{}
+	FormMultipart = formMultipartBinding{}
+	Uri           = uriBinding{}
+	Header        = headerBinding{}
+)
+
+// Default 根据请求方法和 Content-Type 选择 Binding，GET 请求使用 Form
+func Default(method string, contentType string) Binding {
+	if method == http.MethodGet {
+		return Form
+	}
+	switch filterFlags(contentType) {
+	case MIMEJSON:
+		return JSON
+	case MIMEXML, MIMEXML2:
+		return XML
+	case MIMEMultipartPOSTForm:
+		return FormMultipart
+	default:
+		return Form
+	}
+}
+
+// filterFlags 去掉 Content-Type 中 ; 后面的参数
+func filterFlags(content string) string {
+	if i := strings.IndexByte(content, ';'); i >= 0 {
+		content = content[:i]
+	}
+	return strings.TrimSpace(content)
+}
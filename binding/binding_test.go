@@ -0,0 +1,176 @@
+package binding
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type page struct {
+	Page int `form:"page,default=1"`
+	Size int `form:"size,default=20"`
+}
+
+type query struct {
+	page
+	Name     string        `form:"name"`
+	Ids      []int64       `form:"id"`
+	Active   bool          `form:"active"`
+	Score    *float64      `form:"score"`
+	Birthday time.Time     `form:"birthday" time_format:"2006-01-02" time_utc:"1"`
+	Created  time.Time     `form:"created" time_format:"unix"`
+	Timeout  time.Duration `form:"timeout"`
+	Ignored  string        `form:"-"`
+}
+
+func TestQueryBinding(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/?name=tom&id=1&id=2&active=true&score=9.5&birthday=2000-01-02&created=1700000000&timeout=3s&Ignored=x&size=5", nil)
+	var q query
+	if err := Query.Bind(req, &q); err != nil {
+		t.Fatal(err)
+	}
+	if q.Name != "tom" || len(q.Ids) != 2 || q.Ids[1] != 2 || !q.Active || q.Score == nil || *q.Score != 9.5 {
+		t.Fatalf("q = %+v", q)
+	}
+	if q.Page != 1 || q.Size != 5 || q.Ignored != "" || q.Timeout != 3*time.Second {
+		t.Fatalf("q = %+v", q)
+	}
+	if !q.Birthday.Equal(time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)) || q.Created.Unix() != 1700000000 {
+		t.Fatalf("birthday = %v, created = %v", q.Birthday, q.Created)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/?id=x", nil)
+	if err := Query.Bind(req, &q); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+type node struct {
+	Name string `form:"name"`
+	Next *node
+}
+
+type address struct {
+	City string `form:"city"`
+	Zip  string `form:"zip,default=000000"`
+}
+
+type order struct {
+	ID      int      `form:"id"`
+	Address *address `validate:"required"`
+}
+
+func TestNestedPointer(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/?name=a", nil)
+	var n node
+	if err := Query.Bind(req, &n); err != nil {
+		t.Fatal(err)
+	}
+	if n.Name != "a" || n.Next != nil {
+		t.Fatalf("n = %+v", n)
+	}
+
+	// 请求中没有 Address 的字段时保持 nil，required 校验失败
+	req = httptest.NewRequest(http.MethodGet, "/?id=1", nil)
+	var o order
+	if err := Query.Bind(req, &o); err == nil || o.Address != nil {
+		t.Fatalf("address = %+v, err = %v", o.Address, err)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/?id=1&city=sh", nil)
+	o = order{}
+	if err := Query.Bind(req, &o); err != nil {
+		t.Fatal(err)
+	}
+	if o.Address == nil || o.Address.City != "sh" || o.Address.Zip != "000000" {
+		t.Fatalf("address = %+v", o.Address)
+	}
+}
+
+func TestMultipartBinding(t *testing.T) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("title", "report")
+	for _, name := range []string{"a.txt", "b.txt"} {
+		fw, _ := mw.CreateFormFile("files", name)
+		fw.Write([]byte(name))
+	}
+	fw, _ := mw.CreateFormFile("avatar", "me.png")
+	fw.Write([]byte("png"))
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	var form struct {
+		Title  string                  `form:"title"`
+		Avatar *multipart.FileHeader   `form:"avatar"`
+		Files  []*multipart.FileHeader `form:"files"`
+	}
+	b := Default(req.Method, req.Header.Get("Content-Type"))
+	if b != FormMultipart {
+		t.Fatalf("binding = %s", b.Name())
+	}
+	if err := b.Bind(req, &form); err != nil {
+		t.Fatal(err)
+	}
+	if form.Title != "report" || form.Avatar == nil || form.Avatar.Filename != "me.png" || len(form.Files) != 2 {
+		t.Fatalf("form = %+v", form)
+	}
+}
+
+func TestHeaderAndUriBinding(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Api-Version", "2")
+	req.Header.Set("X-Request-Id", "abc")
+	var h struct {
+		Version int    `header:"x-api-version"`
+		ID      string `header:"X-Request-ID"`
+	}
+	if err := Header.Bind(req, &h); err != nil {
+		t.Fatal(err)
+	}
+	if h.Version != 2 || h.ID != "abc" {
+		t.Fatalf("h = %+v", h)
+	}
+
+	var u struct {
+		ID   uint   `uri:"id"`
+		Name string `uri:"name"`
+	}
+	if err := Uri.BindUri(map[string][]string{"id": {"7"}, "name": {"tom"}}, &u); err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != 7 || u.Name != "tom" {
+		t.Fatalf("u = %+v", u)
+	}
+}
+
+func TestDefault(t *testing.T) {
+	tests := []struct {
+		method      string
+		contentType string
+		binding     Binding
+	}{
+		{http.MethodGet, MIMEJSON, Form},
+		{http.MethodPost, "application/json; charset=utf-8", JSON},
+		{http.MethodPut, MIMEXML2, XML},
+		{http.MethodPost, MIMEPOSTForm, Form},
+	}
+	for _, test := range tests {
+		if b := Default(test.method, test.contentType); b != test.binding {
+			t.Fatalf("%s %s: binding = %s", test.method, test.contentType, b.Name())
+		}
+	}
+
+	var obj struct {
+		Name string `json:"name"`
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"tom"}`))
+	if err := JSON.Bind(req, &obj); err != nil || obj.Name != "tom" {
+		t.Fatalf("obj = %+v, err = %v", obj, err)
+	}
+}
@@ -0,0 +1,65 @@
+package binding
+
+import (
+	"errors"
+	"mime/multipart"
+	"net/http"
+)
+
+type formBinding struct{}
+
+type formPostBinding struct{}
+
+type formMultipartBinding struct{}
+
+func (formBinding) Name() string {
+	return "form"
+}
+
+// Bind 绑定查询参数和表单，multipart 表单中的文件也会绑定
+func (formBinding) Bind(req *http.Request, obj any) error {
+	if err := req.ParseForm(); err != nil {
+		return err
+	}
+	if err := req.ParseMultipartForm(defaultMemory); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return err
+	}
+	var files map[string][]*multipart.FileHeader
+	if req.MultipartForm != nil {
+		files = req.MultipartForm.File
+	}
+	if err := mapForm(obj, req.Form, files, "form"); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+func (formPostBinding) Name() string {
+	return "form-urlencoded"
+}
+
+// Bind 只绑定请求体中的表单
+func (formPostBinding) Bind(req *http.Request, obj any) error {
+	if err := req.ParseForm(); err != nil {
+		return err
+	}
+	if err := mapForm(obj, req.PostForm, nil, "form"); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+func (formMultipartBinding) Name() string {
+	return "multipart/form-data"
+}
+
+// Bind 绑定 multipart 表单，*multipart.FileHeader 和 []*multipart.FileHeader 字段绑定上传的文件
+func (formMultipartBinding) Bind(req *http.Request, obj any) error {
+	if err := req.ParseMultipartForm(defaultMemory); err != nil {
+		return err
+	}
+	if err := mapForm(obj, req.MultipartForm.Value, req.MultipartForm.File, "form"); err != nil {
+		return err
+	}
+	return validate(obj)
+}
@@ -0,0 +1,347 @@
+package binding
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	fileHeaderType      = reflect.TypeOf(multipart.FileHeader{})
+	fileHeaderPtrType   = reflect.TypeOf(&multipart.FileHeader{})
+	fileHeadersType     = reflect.TypeOf([]*multipart.FileHeader{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// mapForm 按 tag 把 form 中的值写入结构体，tag 写法为 form:"name,default=value"，
+// 没有 tag 时使用字段名，tag 为 - 时忽略，没有 tag 的结构体字段递归绑定
+func mapForm(obj any, form map[string][]string, files map[string][]*multipart.FileHeader, tag string) error {
+	return mapFormWithKey(obj, form, files, tag, nil)
+}
+
+// mapFormWithKey key 不为 nil 时先用它转换字段名再查找
+func mapFormWithKey(obj any, form map[string][]string, files map[string][]*multipart.FileHeader, tag string, key func(string) string) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return errors.New("binding: obj must be a non-nil pointer")
+	}
+	v = v.Elem()
+	switch v.Kind() {
+	case reflect.Struct:
+		m := &formMapper{form: form, files: files, tag: tag, key: key}
+		_, err := m.mapStruct(v)
+		return err
+	case reflect.Map:
+		return mapToMap(v, form)
+	}
+	return fmt.Errorf("binding: unsupported type %s", v.Type())
+}
+
+// mapToMap 支持 map[string]string 和 map[string][]string
+func mapToMap(v reflect.Value, form map[string][]string) error {
+	t := v.Type()
+	if t.Key().Kind() != reflect.String {
+		return fmt.Errorf("binding: unsupported type %s", t)
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(t))
+	}
+	for key, values := range form {
+		switch {
+		case t.Elem().Kind() == reflect.String:
+			if len(values) > 0 {
+				v.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(values[0]))
+			}
+		case t.Elem() == reflect.TypeOf([]string{}):
+			v.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(values))
+		default:
+			return fmt.Errorf("binding: unsupported type %s", t)
+		}
+	}
+	return nil
+}
+
+type formMapper struct {
+	form  map[string][]string
+	files map[string][]*multipart.FileHeader
+	tag   string
+	key   func(string) string
+	// visiting 正在绑定的结构体类型，避免自引用的指针字段无限递归
+	visiting map[reflect.Type]bool
+}
+
+// mapStruct 返回的 set 表示请求中是否有值写入了结构体，默认值不算
+func (m *formMapper) mapStruct(v reflect.Value) (set bool, err error) {
+	t := v.Type()
+	if m.visiting == nil {
+		m.visiting = make(map[reflect.Type]bool)
+	}
+	m.visiting[t] = true
+	defer delete(m.visiting, t)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !(field.Anonymous && field.Type.Kind() == reflect.Struct) {
+			continue
+		}
+		tagValue := field.Tag.Get(m.tag)
+		if tagValue == "-" {
+			continue
+		}
+		name, defaultValue, hasDefault := parseTag(tagValue)
+		fv := v.Field(i)
+		if name == "" && isNestedStruct(field.Type) {
+			nestedSet, err := m.mapNested(fv)
+			if err != nil {
+				return false, err
+			}
+			set = set || nestedSet
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if m.key != nil {
+			name = m.key(name)
+		}
+		if isFileType(field.Type) {
+			if err := setFiles(fv, m.files[name]); err != nil {
+				return false, fmt.Errorf("binding: field %s: %w", field.Name, err)
+			}
+			set = set || len(m.files[name]) > 0
+			continue
+		}
+		values, ok := m.form[name]
+		if !ok || len(values) == 0 {
+			if !hasDefault {
+				continue
+			}
+			values = []string{defaultValue}
+		} else {
+			set = true
+		}
+		if err := setField(fv, values, field); err != nil {
+			return false, fmt.Errorf("binding: field %s: %w", field.Name, err)
+		}
+	}
+	return set, nil
+}
+
+// mapNested 绑定没有 tag 的结构体字段，值为 nil 的指针先绑定到临时值，
+// 请求中有对应的值时才赋值，这样 validate:"required" 仍然可以检查出缺失的结构体
+func (m *formMapper) mapNested(fv reflect.Value) (bool, error) {
+	if fv.Kind() != reflect.Pointer {
+		return m.mapStruct(fv)
+	}
+	if m.visiting[fv.Type().Elem()] {
+		return false, nil
+	}
+	if !fv.IsNil() {
+		return m.mapStruct(fv.Elem())
+	}
+	tmp := reflect.New(fv.Type().Elem())
+	set, err := m.mapStruct(tmp.Elem())
+	if err != nil || !set {
+		return false, err
+	}
+	fv.Set(tmp)
+	return true, nil
+}
+
+// parseTag name,default=value
+func parseTag(tag string) (name string, defaultValue string, hasDefault bool) {
+	name, opts, _ := strings.Cut(tag, ",")
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if k, v, ok := strings.Cut(opt, "="); ok && k == "default" {
+			return name, v, true
+		}
+	}
+	return name, "", false
+}
+
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType || t == fileHeaderType {
+		return false
+	}
+	return !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func isFileType(t reflect.Type) bool {
+	return t == fileHeaderPtrType || t == fileHeadersType || t == fileHeaderType
+}
+
+func setFiles(v reflect.Value, files []*multipart.FileHeader) error {
+	if len(files) == 0 {
+		return nil
+	}
+	switch v.Type() {
+	case fileHeaderPtrType:
+		v.Set(reflect.ValueOf(files[0]))
+	case fileHeaderType:
+		v.Set(reflect.ValueOf(*files[0]))
+	case fileHeadersType:
+		v.Set(reflect.ValueOf(files))
+	}
+	return nil
+}
+
+func setField(v reflect.Value, values []string, field reflect.StructField) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if err := setField(elem.Elem(), values, field); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(values[0]))
+			return nil
+		}
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(slice.Index(i), value, field); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	case reflect.Array:
+		if len(values) != v.Len() {
+			return fmt.Errorf("%q is not valid value for %s", values, v.Type())
+		}
+		for i, value := range values {
+			if err := setValue(v.Index(i), value, field); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return setValue(v, values[0], field)
+}
+
+func setValue(v reflect.Value, value string, field reflect.StructField) error {
+	switch v.Type() {
+	case timeType:
+		return setTime(v, value, field)
+	case durationType:
+		if value == "" {
+			value = "0"
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		if value == "" {
+			value = "false"
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value == "" {
+			value = "0"
+		}
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value == "" {
+			value = "0"
+		}
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if value == "" {
+			value = "0"
+		}
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Interface:
+		v.Set(reflect.ValueOf(value))
+	case reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), value, field); err != nil {
+			return err
+		}
+		v.Set(elem)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// setTime time_format 指定格式，默认 RFC3339，unix 和 unixnano 表示时间戳，
+// time_utc 为 1 时使用 UTC，time_location 指定时区
+func setTime(v reflect.Value, value string, field reflect.StructField) error {
+	if value == "" {
+		v.Set(reflect.ValueOf(time.Time{}))
+		return nil
+	}
+	format := field.Tag.Get("time_format")
+	if format == "" {
+		format = time.RFC3339
+	}
+	switch format {
+	case "unix", "unixnano":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		t := time.Unix(n, 0)
+		if format == "unixnano" {
+			t = time.Unix(0, n)
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+	loc := time.Local
+	if isUTC, _ := strconv.ParseBool(field.Tag.Get("time_utc")); isUTC {
+		loc = time.UTC
+	}
+	if name := field.Tag.Get("time_location"); name != "" {
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return err
+		}
+		loc = l
+	}
+	t, err := time.ParseInLocation(format, value, loc)
+	if err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(t))
+	return nil
+}
@@ -0,0 +1,20 @@
+package binding
+
+import (
+	"net/http"
+	"net/textproto"
+)
+
+type headerBinding struct{}
+
+func (headerBinding) Name() string {
+	return "header"
+}
+
+// Bind 按 header tag 绑定请求头，tag 的大小写不影响匹配
+func (headerBinding) Bind(req *http.Request, obj any) error {
+	if err := mapFormWithKey(obj, req.Header, nil, "header", textproto.CanonicalMIMEHeaderKey); err != nil {
+		return err
+	}
+	return validate(obj)
+}
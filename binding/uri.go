@@ -0,0 +1,15 @@
+package binding
+
+type uriBinding struct{}
+
+func (uriBinding) Name() string {
+	return "uri"
+}
+
+// BindUri 按 uri tag 绑定路由参数
+func (uriBinding) BindUri(params map[string][]string, obj any) error {
+	if err := mapForm(obj, params, nil, "uri"); err != nil {
+		return err
+	}
+	return validate(obj)
+}
@@ -0,0 +1,20 @@
+package binding
+
+import (
+	"github.com/JUYAFEI/go-framework/validator"
+)
+
+// StructValidator 绑定完成后校验结构体
+type StructValidator interface {
+	Struct(obj any) error
+}
+
+// Validator 绑定使用的校验器，默认按 validate tag 校验，设为 nil 关闭校验
+var Validator StructValidator = validator.Default()
+
+func validate(obj any) error {
+	if Validator == nil {
+		return nil
+	}
+	return Validator.Struct(obj)
+}
@@ -0,0 +1,34 @@
+package binding
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+)
+
+type xmlBinding struct{}
+
+func (xmlBinding) Name() string {
+	return "xml"
+}
+
+func (xmlBinding) Bind(req *http.Request, obj any) error {
+	if req == nil || req.Body == nil {
+		return errors.New("invalid request")
+	}
+	return decodeXML(req.Body, obj)
+}
+
+// BindBody 从字节数组中解码
+func (xmlBinding) BindBody(body []byte, obj any) error {
+	return decodeXML(bytes.NewReader(body), obj)
+}
+
+func decodeXML(r io.Reader, obj any) error {
+	if err := xml.NewDecoder(r).Decode(obj); err != nil {
+		return err
+	}
+	return validate(obj)
+}
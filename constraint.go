@@ -0,0 +1,59 @@
+package go_framework
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ParamMatcher 路由参数约束，返回 false 时该路由不匹配
+type ParamMatcher func(value string) bool
+
+var (
+	paramTypesLock sync.RWMutex
+	paramTypes     = map[string]ParamMatcher{
+		"int": func(value string) bool {
+			_, err := strconv.ParseInt(value, 10, 64)
+			return err == nil
+		},
+		"uint": func(value string) bool {
+			_, err := strconv.ParseUint(value, 10, 64)
+			return err == nil
+		},
+		"float": func(value string) bool {
+			_, err := strconv.ParseFloat(value, 64)
+			return err == nil
+		},
+		"alpha": regexp.MustCompile(`^[a-zA-Z]+$`).MatchString,
+		"alnum": regexp.MustCompile(`^[a-zA-Z0-9]+$`).MatchString,
+		"uuid":  regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`).MatchString,
+		"date": func(value string) bool {
+			_, err := time.Parse("2006-01-02", value)
+			return err == nil
+		},
+	}
+)
+
+// RegisterParamType 注册路由参数类型，注册后可以在路由中使用 :name<typ>，需在注册路由前调用
+func RegisterParamType(typ string, matcher ParamMatcher) {
+	paramTypesLock.Lock()
+	paramTypes[typ] = matcher
+	paramTypesLock.Unlock()
+}
+
+// compileConstraint 约束是已注册的类型时使用类型校验，否则作为正则匹配整个参数值
+func compileConstraint(constraint string) ParamMatcher {
+	paramTypesLock.RLock()
+	matcher, ok := paramTypes[constraint]
+	paramTypesLock.RUnlock()
+	if ok {
+		return matcher
+	}
+	re, err := regexp.Compile("^(?:" + constraint + ")$")
+	if err != nil {
+		panic(fmt.Sprintf("invalid param constraint <%s>: %v", constraint, err))
+	}
+	return re.MatchString
+}
@@ -0,0 +1,48 @@
+package go_framework
+
+import (
+	"net"
+	"strings"
+)
+
+// hostPattern Host 匹配规则，按 . 分段，{name} 匹配任意一段并捕获，* 匹配任意一段，其余忽略大小写比较，
+// 规则中没有端口时忽略请求中的端口
+type hostPattern struct {
+	labels   []string
+	withPort bool
+}
+
+func newHostPattern(pattern string) *hostPattern {
+	return &hostPattern{
+		labels:   strings.Split(pattern, "."),
+		withPort: strings.Contains(pattern, ":"),
+	}
+}
+
+func (h *hostPattern) match(host string, params *Params) bool {
+	if !h.withPort {
+		if name, _, err := net.SplitHostPort(host); err == nil {
+			host = name
+		}
+	}
+	labels := strings.Split(host, ".")
+	if len(labels) != len(h.labels) {
+		return false
+	}
+	mark := len(*params)
+	for i, label := range h.labels {
+		switch {
+		case label == "*":
+		case len(label) > 2 && label[0] == '{' && label[len(label)-1] == '}':
+			if labels[i] == "" {
+				*params = (*params)[:mark]
+				return false
+			}
+			*params = append(*params, Param{Key: label[1 : len(label)-1], Value: labels[i]})
+		case !strings.EqualFold(label, labels[i]):
+			*params = (*params)[:mark]
+			return false
+		}
+	}
+	return true
+}
@@ -60,7 +60,7 @@ func LoggerWithConfig(config LoggerConfig, next HandlerFunc) HandlerFunc {
 		ip, _, _ := net.SplitHostPort(strings.TrimSpace(c.R.RemoteAddr))
 		clientIp := net.ParseIP(ip)
 		method := c.R.Method
-		statusCode := c.StatusCode
+		statusCode := c.W.Status()
 
 		if raw != "" {
 			path = path + "?" + raw
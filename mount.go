@@ -0,0 +1,83 @@
+package go_framework
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// WrapH 把 http.Handler 转换为 HandlerFunc
+func WrapH(h http.Handler) HandlerFunc {
+	return func(ctx *Context) {
+		h.ServeHTTP(ctx.W, ctx.R)
+	}
+}
+
+// WrapF 把 http.HandlerFunc 转换为 HandlerFunc
+func WrapF(f http.HandlerFunc) HandlerFunc {
+	return WrapH(f)
+}
+
+// WrapMiddleware 把 func(http.Handler) http.Handler 形式的标准中间件转换为 MiddlewareFunc，
+// 中间件替换的 ResponseWriter 和 Request 对后面的 handler 生效
+func WrapMiddleware(m func(http.Handler) http.Handler) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			w, req := ctx.W, ctx.R
+			defer func() {
+				ctx.W, ctx.R = w, req
+			}()
+			m(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				ctx.W, ctx.R = newResponseWriter(w), req
+				next(ctx)
+				// 中间件替换了 ResponseWriter 时，只设置了状态码的响应要在回到中间件之前发送
+				if _, ok := w.(ResponseWriter); !ok {
+					ctx.W.WriteHeaderNow()
+				}
+			})).ServeHTTP(ctx.W, ctx.R)
+		}
+	}
+}
+
+// Wrap 在分组中注册 http.Handler，匹配所有请求方法，请求路径保持不变
+func (r *RouterGroup) Wrap(name string, h http.Handler, middlewareFunc ...MiddlewareFunc) *Route {
+	return r.Any(name, WrapH(h), middlewareFunc...)
+}
+
+// Mount 把 http.Handler 挂载到分组的 prefix 下，如另一个 Engine 或 http.FileServer，
+// 转发前去掉分组和 prefix 前缀，编码过的路径如 %2F 保持不变，会经过分组中间件和 middlewareFunc。
+// net/http/pprof 按完整路径匹配，需要使用 Wrap 注册，如 engine.Group("debug").Wrap("/pprof/*", http.DefaultServeMux)
+func (r *RouterGroup) Mount(prefix string, h http.Handler, middlewareFunc ...MiddlewareFunc) {
+	prefix = joinPrefix("", prefix)
+	segments := strings.Count(r.prefix+prefix, "/")
+	handler := func(ctx *Context) {
+		req := new(http.Request)
+		*req = *ctx.R
+		u := *ctx.R.URL
+		u.Path = stripSegments(u.Path, segments)
+		if u.RawPath != "" {
+			u.RawPath = stripSegments(u.RawPath, segments)
+			if p, err := url.PathUnescape(u.RawPath); err != nil || p != u.Path {
+				u.RawPath = ""
+			}
+		}
+		req.URL = &u
+		h.ServeHTTP(ctx.W, req)
+	}
+	if prefix != "" {
+		r.Any(prefix, handler, middlewareFunc...)
+	}
+	r.Any(prefix+"/*", handler, middlewareFunc...)
+}
+
+// stripSegments 去掉路径开头的 n 段，如 /api/debug/vars 去掉 2 段后为 /vars
+func stripSegments(p string, n int) string {
+	for i := 0; i < n; i++ {
+		next := strings.IndexByte(p[1:], '/')
+		if next < 0 {
+			return "/"
+		}
+		p = p[next+1:]
+	}
+	return p
+}
@@ -0,0 +1,203 @@
+package go_framework
+
+import (
+	"errors"
+	"fmt"
+	"github.com/JUYAFEI/go-framework/binding"
+	"github.com/JUYAFEI/go-framework/render"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	MIMEJSON     = binding.MIMEJSON
+	MIMEXML      = binding.MIMEXML
+	MIMEXML2     = binding.MIMEXML2
+	MIMEHTML     = "text/html"
+	MIMEPlain    = "text/plain"
+	MIMEYAML     = "application/yaml"
+	MIMETOML     = "application/toml"
+	MIMEMsgPack  = "application/msgpack"
+	MIMEProtoBuf = "application/x-protobuf"
+	MIMECSV      = "text/csv"
+)
+
+// ErrNotAcceptable 客户端接受的格式服务端都没有提供
+var ErrNotAcceptable = errors.New("the accepted formats are not offered by the server")
+
+// Negotiate Context.Negotiate 的配置，JSONData、XMLData、HTMLData 没有设置时使用 Data
+type Negotiate struct {
+	// Offered 服务端提供的格式，按优先级排列
+	Offered []string
+	// HTMLName 不为空时使用 Engine 加载的模板渲染，否则 HTML 数据必须是 string
+	HTMLName string
+	HTMLData any
+	JSONData any
+	XMLData  any
+	Data     any
+	// Renders 其他格式使用的 render.Render，优先于内置格式
+	Renders map[string]render.Render
+}
+
+// acceptRange Accept 中的一项，如 text/html;q=0.8
+type acceptRange struct {
+	typ     string
+	subtype string
+	q       float64
+	// specificity */* 为 0，type/* 为 1，type/subtype 为 2，带参数时再加 1
+	specificity int
+}
+
+// parseAccept 解析 Accept 请求头，格式错误的项会被忽略
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, item := range strings.Split(header, ",") {
+		value, params, _ := strings.Cut(item, ";")
+		typ, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(value)), "/")
+		if !ok || typ == "" || subtype == "" || (typ == "*" && subtype != "*") {
+			continue
+		}
+		r := acceptRange{typ: typ, subtype: subtype, q: 1}
+		switch {
+		case typ == "*":
+		case subtype == "*":
+			r.specificity = 1
+		default:
+			r.specificity = 2
+		}
+		for _, param := range strings.Split(params, ";") {
+			k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if k = strings.ToLower(strings.TrimSpace(k)); k == "" {
+				continue
+			}
+			if k != "q" {
+				r.specificity++
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil || q < 0 || q > 1 {
+				q = 0
+			}
+			r.q = q
+			// q 之后的参数是 accept-ext，不影响匹配
+			break
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+func (r acceptRange) match(mediaType string) bool {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	return (r.typ == "*" || r.typ == typ) && (r.subtype == "*" || r.subtype == subtype)
+}
+
+// mediaType 去掉参数并转为小写，如 text/html; charset=utf-8 返回 text/html
+func mediaType(offer string) string {
+	t, _, _ := strings.Cut(offer, ";")
+	return strings.ToLower(strings.TrimSpace(t))
+}
+
+// Accepted Accept 请求头中客户端接受的格式，按 q 值从高到低排列，不包含 q=0 的格式
+func (c *Context) Accepted() []string {
+	ranges := parseAccept(c.R.Header.Get("Accept"))
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+	accepted := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		if r.q > 0 {
+			accepted = append(accepted, r.typ+"/"+r.subtype)
+		}
+	}
+	return accepted
+}
+
+// NegotiateFormat 按 Accept 请求头从 offered 中选出最合适的格式，
+// 每个格式使用最具体的匹配项的 q 值，q 值相同时按 offered 的顺序，
+// 没有 Accept 请求头时返回第一个，都不接受时返回空字符串
+func (c *Context) NegotiateFormat(offered ...string) string {
+	if len(offered) == 0 {
+		panic("you must provide at least one offer")
+	}
+	header := c.R.Header.Get("Accept")
+	if strings.TrimSpace(header) == "" {
+		return offered[0]
+	}
+	ranges := parseAccept(header)
+	best, bestQ := "", 0.0
+	for _, offer := range offered {
+		t := mediaType(offer)
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			if r.match(t) && r.specificity > specificity {
+				q, specificity = r.q, r.specificity
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// Negotiate 按 Accept 请求头选择格式渲染，都不接受时返回 406 和 ErrNotAcceptable
+func (c *Context) Negotiate(code int, config Negotiate) error {
+	offered := config.Offered
+	if len(offered) == 0 {
+		offered = []string{MIMEJSON, MIMEXML, MIMEHTML, MIMEPlain}
+	}
+	c.W.Header().Add("Vary", "Accept")
+	format := c.NegotiateFormat(offered...)
+	if format == "" {
+		c.AbortWithStatus(http.StatusNotAcceptable)
+		return ErrNotAcceptable
+	}
+	if r, ok := config.Renders[format]; ok {
+		return c.Render(code, r)
+	}
+	switch mediaType(format) {
+	case MIMEJSON:
+		return c.JSON(code, chooseData(config.JSONData, config.Data))
+	case MIMEXML, MIMEXML2:
+		return c.XML(code, chooseData(config.XMLData, config.Data))
+	case MIMEHTML:
+		data := chooseData(config.HTMLData, config.Data)
+		if config.HTMLName != "" {
+			return c.Render(code, render.HTML{
+				IsTemplate: true,
+				Name:       config.HTMLName,
+				Data:       data,
+				Template:   c.Engine.HTMLRender.Template,
+			})
+		}
+		html, ok := data.(string)
+		if !ok {
+			return errors.New("negotiate: HTML data must be a string when HTMLName is empty")
+		}
+		return c.Render(code, render.HTML{Data: html})
+	case MIMEPlain:
+		return c.String(code, "%v", config.Data)
+	case MIMEYAML:
+		return c.YAML(code, config.Data)
+	case MIMETOML:
+		return c.TOML(code, config.Data)
+	case MIMEMsgPack:
+		return c.MsgPack(code, config.Data)
+	case MIMEProtoBuf:
+		return c.ProtoBuf(code, config.Data)
+	case MIMECSV:
+		return c.CSV(code, config.Data)
+	}
+	c.AbortWithStatus(http.StatusInternalServerError)
+	return fmt.Errorf("negotiate: no render for offered format %s", format)
+}
+
+func chooseData(custom any, data any) any {
+	if custom != nil {
+		return custom
+	}
+	return data
+}
@@ -25,15 +25,16 @@ func Recovery(next HandlerFunc) HandlerFunc {
 	return func(ctx *Context) {
 		defer func() {
 			if err := recover(); err != nil {
-				err2 := err.(error)
-				if err2 != nil {
+				if err2, ok := err.(error); ok {
 					var msError *goerror.GoError
 					if errors.As(err2, &msError) {
+						ctx.Abort()
 						msError.ExecResult()
 						return
 					}
 				}
 				ctx.Logger.Error(detailMsg(err))
+				ctx.Abort()
 				ctx.Fail(http.StatusInternalServerError, "Internal Server Error")
 			}
 		}()
@@ -57,6 +57,13 @@ func (r *MsEtcdRegister) RegisterService(serviceName string, host string, port i
 	return err
 }
 
+func (r *MsEtcdRegister) DeregisterService(serviceName string, host string, port int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := r.cli.Delete(ctx, serviceName)
+	return err
+}
+
 func (r *MsEtcdRegister) GetValue(serviceName string) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
@@ -123,6 +123,16 @@ func (r *MsNacosRegister) RegisterService(serviceName string, host string, port
 	})
 	return err
 }
+func (r *MsNacosRegister) DeregisterService(serviceName string, host string, port int) error {
+	_, err := r.cli.DeregisterInstance(vo.DeregisterInstanceParam{
+		Ip:          host,
+		Port:        uint64(port),
+		ServiceName: serviceName,
+		Ephemeral:   true,
+	})
+	return err
+}
+
 func (r *MsNacosRegister) GetValue(serviceName string) (string, error) {
 	instance, err := r.cli.SelectOneHealthyInstance(vo.SelectOneHealthInstanceParam{
 		ServiceName: serviceName,
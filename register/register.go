@@ -18,6 +18,7 @@ type Option struct {
 type MsRegister interface {
 	CreateCli(option Option) error
 	RegisterService(serviceName string, host string, port int) error
+	DeregisterService(serviceName string, host string, port int) error
 	GetValue(serviceName string) (string, error)
 	Close() error
 }
@@ -0,0 +1,209 @@
+package render
+
+import (
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// CSV 逐行写入 CSV，Data 可以是结构体切片、[][]string，或者元素为结构体或 []string 的 channel，
+// channel 关闭时结束，每收到一行就 flush 一次。结构体的列名使用 csv tag，没有时使用字段名，
+// - 表示忽略，time.Time 按 time_format tag 格式化，默认 RFC3339
+type CSV struct {
+	Data any
+	// Filename 不为空时作为附件下载
+	Filename string
+	// NoHeader 为 true 时结构体不输出表头
+	NoHeader bool
+	// Comma 分隔符，默认为 ,
+	Comma rune
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+func (r CSV) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	if r.Filename != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": r.Filename}))
+	}
+	v := reflect.ValueOf(r.Data)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array && v.Kind() != reflect.Chan {
+		return fmt.Errorf("render: csv data must be a slice, array or channel, got %T", r.Data)
+	}
+	rows, err := newCSVRows(v.Type().Elem())
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if r.Comma != 0 {
+		cw.Comma = r.Comma
+	}
+	if header := rows.header(); header != nil && !r.NoHeader {
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+	}
+	if v.Kind() == reflect.Chan {
+		flusher, _ := w.(http.Flusher)
+		for {
+			item, ok := v.Recv()
+			if !ok {
+				break
+			}
+			if err := cw.Write(rows.row(item)); err != nil {
+				return err
+			}
+			cw.Flush()
+			if err := cw.Error(); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return nil
+	}
+	for i := 0; i < v.Len(); i++ {
+		if err := cw.Write(rows.row(v.Index(i))); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (r CSV) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "text/csv; charset=utf-8")
+}
+
+// csvColumn 结构体中的一列
+type csvColumn struct {
+	name       string
+	index      []int
+	timeFormat string
+}
+
+type csvRows struct {
+	columns []csvColumn
+	// raw 元素为 []string 时原样输出
+	raw bool
+}
+
+func newCSVRows(elem reflect.Type) (*csvRows, error) {
+	if elem.Kind() == reflect.Slice && elem.Elem().Kind() == reflect.String {
+		return &csvRows{raw: true}, nil
+	}
+	t := elem
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, errors.New("render: csv rows must be structs or []string, got " + elem.String())
+	}
+	return &csvRows{columns: csvColumns(t, nil, nil)}, nil
+}
+
+// csvColumns 收集结构体的列，匿名结构体的字段提升到外层
+func csvColumns(t reflect.Type, prefix []int, columns []csvColumn) []csvColumn {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := sf.Tag.Get("csv")
+		if name == "-" {
+			continue
+		}
+		index := append(append([]int{}, prefix...), i)
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct && ft != timeType {
+			columns = csvColumns(ft, index, columns)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		columns = append(columns, csvColumn{name: name, index: index, timeFormat: sf.Tag.Get("time_format")})
+	}
+	return columns
+}
+
+func (r *csvRows) header() []string {
+	if r.raw {
+		return nil
+	}
+	header := make([]string, len(r.columns))
+	for i, column := range r.columns {
+		header[i] = column.name
+	}
+	return header
+}
+
+func (r *csvRows) row(item reflect.Value) []string {
+	if r.raw {
+		row := make([]string, item.Len())
+		for i := range row {
+			row[i] = item.Index(i).String()
+		}
+		return row
+	}
+	for item.Kind() == reflect.Pointer {
+		if item.IsNil() {
+			return make([]string, len(r.columns))
+		}
+		item = item.Elem()
+	}
+	row := make([]string, len(r.columns))
+	for i, column := range r.columns {
+		if field, err := item.FieldByIndexErr(column.index); err == nil {
+			row[i] = csvValue(field, column.timeFormat)
+		}
+	}
+	return row
+}
+
+func csvValue(v reflect.Value, timeFormat string) string {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if !v.CanInterface() {
+		return ""
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		if timeFormat == "" {
+			timeFormat = time.RFC3339
+		}
+		return t.Format(timeFormat)
+	}
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err == nil {
+			return string(text)
+		}
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,331 @@
+package render
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MsgPack 按 MessagePack 规范编码，结构体编码为 map，
+// 字段名使用 msgpack tag，没有时使用 json tag，- 表示忽略，omitempty 忽略零值，
+// 指针按指向的值编码，time.Time 编码为时间戳扩展类型，实现了 encoding.BinaryMarshaler 的类型编码为 bin
+type MsgPack struct {
+	Data any
+}
+
+func (r MsgPack) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	data, err := MarshalMsgPack(r.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func (r MsgPack) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/msgpack")
+}
+
+// MarshalMsgPack 把 v 编码为 MessagePack
+func MarshalMsgPack(v any) ([]byte, error) {
+	var e msgpackEncoder
+	if err := e.encode(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	binaryMarshalerType = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+)
+
+// timestampExt MessagePack 时间戳扩展类型 -1
+const timestampExt = 0xff
+
+type msgpackEncoder struct {
+	buf bytes.Buffer
+}
+
+func (e *msgpackEncoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		e.buf.WriteByte(0xc0)
+		return nil
+	}
+	// 先解引用，*time.Time 等指针按指向的值编码
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			e.buf.WriteByte(0xc0)
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Type() == timeType && v.CanInterface() {
+		e.encodeTime(v.Interface().(time.Time))
+		return nil
+	}
+	if m, ok := binaryMarshaler(v); ok {
+		data, err := m.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		e.encodeBytes(data)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf.WriteByte(0xc3)
+		} else {
+			e.buf.WriteByte(0xc2)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.encodeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.encodeUint(v.Uint())
+	case reflect.Float32:
+		e.buf.WriteByte(0xca)
+		e.buf.Write(binary.BigEndian.AppendUint32(nil, math.Float32bits(float32(v.Float()))))
+	case reflect.Float64:
+		e.buf.WriteByte(0xcb)
+		e.buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(v.Float())))
+	case reflect.String:
+		e.encodeString(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			e.buf.WriteByte(0xc0)
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.encodeBytes(v.Bytes())
+			return nil
+		}
+		return e.encodeArray(v)
+	case reflect.Array:
+		return e.encodeArray(v)
+	case reflect.Map:
+		if v.IsNil() {
+			e.buf.WriteByte(0xc0)
+			return nil
+		}
+		return e.encodeMap(v)
+	case reflect.Struct:
+		return e.encodeStruct(v)
+	default:
+		return fmt.Errorf("render: msgpack unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// binaryMarshaler 值或者可寻址值的指针实现了 encoding.BinaryMarshaler
+func binaryMarshaler(v reflect.Value) (encoding.BinaryMarshaler, bool) {
+	if !v.CanInterface() {
+		return nil, false
+	}
+	if v.Type().Implements(binaryMarshalerType) {
+		return v.Interface().(encoding.BinaryMarshaler), true
+	}
+	if v.CanAddr() && reflect.PointerTo(v.Type()).Implements(binaryMarshalerType) {
+		return v.Addr().Interface().(encoding.BinaryMarshaler), true
+	}
+	return nil, false
+}
+
+func (e *msgpackEncoder) encodeInt(n int64) {
+	switch {
+	case n >= 0:
+		e.encodeUint(uint64(n))
+	case n >= -32:
+		e.buf.WriteByte(byte(n))
+	case n >= math.MinInt8:
+		e.buf.Write([]byte{0xd0, byte(n)})
+	case n >= math.MinInt16:
+		e.buf.WriteByte(0xd1)
+		e.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	case n >= math.MinInt32:
+		e.buf.WriteByte(0xd2)
+		e.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	default:
+		e.buf.WriteByte(0xd3)
+		e.buf.Write(binary.BigEndian.AppendUint64(nil, uint64(n)))
+	}
+}
+
+func (e *msgpackEncoder) encodeUint(n uint64) {
+	switch {
+	case n <= 0x7f:
+		e.buf.WriteByte(byte(n))
+	case n <= math.MaxUint8:
+		e.buf.Write([]byte{0xcc, byte(n)})
+	case n <= math.MaxUint16:
+		e.buf.WriteByte(0xcd)
+		e.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	case n <= math.MaxUint32:
+		e.buf.WriteByte(0xce)
+		e.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	default:
+		e.buf.WriteByte(0xcf)
+		e.buf.Write(binary.BigEndian.AppendUint64(nil, n))
+	}
+}
+
+func (e *msgpackEncoder) encodeString(s string) {
+	n := len(s)
+	switch {
+	case n <= 31:
+		e.buf.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		e.buf.Write([]byte{0xd9, byte(n)})
+	case n <= math.MaxUint16:
+		e.buf.WriteByte(0xda)
+		e.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		e.buf.WriteByte(0xdb)
+		e.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+	e.buf.WriteString(s)
+}
+
+func (e *msgpackEncoder) encodeBytes(b []byte) {
+	n := len(b)
+	switch {
+	case n <= math.MaxUint8:
+		e.buf.Write([]byte{0xc4, byte(n)})
+	case n <= math.MaxUint16:
+		e.buf.WriteByte(0xc5)
+		e.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		e.buf.WriteByte(0xc6)
+		e.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+	e.buf.Write(b)
+}
+
+func (e *msgpackEncoder) writeArrayHeader(n int) {
+	switch {
+	case n <= 15:
+		e.buf.WriteByte(0x90 | byte(n))
+	case n <= math.MaxUint16:
+		e.buf.WriteByte(0xdc)
+		e.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		e.buf.WriteByte(0xdd)
+		e.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+}
+
+func (e *msgpackEncoder) writeMapHeader(n int) {
+	switch {
+	case n <= 15:
+		e.buf.WriteByte(0x80 | byte(n))
+	case n <= math.MaxUint16:
+		e.buf.WriteByte(0xde)
+		e.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		e.buf.WriteByte(0xdf)
+		e.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+}
+
+func (e *msgpackEncoder) encodeArray(v reflect.Value) error {
+	e.writeArrayHeader(v.Len())
+	for i := 0; i < v.Len(); i++ {
+		if err := e.encode(v.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeMap 字符串键按字典序排列，保证输出稳定
+func (e *msgpackEncoder) encodeMap(v reflect.Value) error {
+	keys := v.MapKeys()
+	if v.Type().Key().Kind() == reflect.String {
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+	}
+	e.writeMapHeader(len(keys))
+	for _, key := range keys {
+		if err := e.encode(key); err != nil {
+			return err
+		}
+		if err := e.encode(v.MapIndex(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type msgpackField struct {
+	name  string
+	value reflect.Value
+}
+
+func (e *msgpackEncoder) encodeStruct(v reflect.Value) error {
+	fields := msgpackFields(v, nil)
+	e.writeMapHeader(len(fields))
+	for _, field := range fields {
+		e.encodeString(field.name)
+		if err := e.encode(field.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// msgpackFields 收集需要编码的字段，匿名结构体的字段提升到外层
+func msgpackFields(v reflect.Value, fields []msgpackField) []msgpackField {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("msgpack")
+		if !ok {
+			tag = sf.Tag.Get("json")
+		}
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fv := v.Field(i)
+		if sf.Anonymous && name == "" {
+			ft := fv
+			if ft.Kind() == reflect.Pointer {
+				if ft.IsNil() {
+					continue
+				}
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && ft.Type() != timeType {
+				fields = msgpackFields(ft, fields)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if strings.Contains(","+opts+",", ",omitempty,") && fv.IsZero() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, msgpackField{name: name, value: fv})
+	}
+	return fields
+}
+
+// encodeTime 使用 timestamp 96 格式，秒和纳秒都能完整保存
+func (e *msgpackEncoder) encodeTime(t time.Time) {
+	e.buf.Write([]byte{0xc7, 12, timestampExt})
+	e.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(t.Nanosecond())))
+	e.buf.Write(binary.BigEndian.AppendUint64(nil, uint64(t.Unix())))
+}
@@ -0,0 +1,342 @@
+package render
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// msgpackDecoder 按 MessagePack 规范独立实现的解码器，用来检查编码结果，
+// 整数解码为 int64，超出范围的无符号整数为 uint64，浮点数为 float64，
+// map 的键都是字符串时解码为 map[string]any，时间戳扩展类型解码为 time.Time
+type msgpackDecoder struct {
+	data []byte
+	pos  int
+}
+
+func decodeMsgPack(data []byte) (any, error) {
+	d := &msgpackDecoder{data: data}
+	v, err := d.decode()
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, fmt.Errorf("%d trailing bytes", len(d.data)-d.pos)
+	}
+	return v, nil
+}
+
+func (d *msgpackDecoder) next(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.data) {
+		return nil, fmt.Errorf("unexpected end at %d", d.pos)
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *msgpackDecoder) uint(n int) (uint64, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return 0, err
+	}
+	var u uint64
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+	return u, nil
+}
+
+func (d *msgpackDecoder) decode() (any, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	c := b[0]
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return d.decodeMap(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return d.decodeArray(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		return d.decodeString(int(c & 0x1f))
+	}
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		p, err := d.next(int(n))
+		return append([]byte{}, p...), err
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.uint(1 << (c - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeExt(int(n))
+	case 0xca:
+		u, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(u))), err
+	case 0xcb:
+		u, err := d.uint(8)
+		return math.Float64frombits(u), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := d.uint(1 << (c - 0xcc))
+		if u > math.MaxInt64 {
+			return u, err
+		}
+		return int64(u), err
+	case 0xd0:
+		u, err := d.uint(1)
+		return int64(int8(u)), err
+	case 0xd1:
+		u, err := d.uint(2)
+		return int64(int16(u)), err
+	case 0xd2:
+		u, err := d.uint(4)
+		return int64(int32(u)), err
+	case 0xd3:
+		u, err := d.uint(8)
+		return int64(u), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.decodeExt(1 << (c - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(int(n))
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(int(n))
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(int(n))
+	}
+	return nil, fmt.Errorf("unknown format 0x%02x at %d", c, d.pos-1)
+}
+
+func (d *msgpackDecoder) decodeString(n int) (any, error) {
+	b, err := d.next(n)
+	return string(b), err
+}
+
+func (d *msgpackDecoder) decodeArray(n int) (any, error) {
+	a := make([]any, n)
+	for i := range a {
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		a[i] = v
+	}
+	return a, nil
+}
+
+func (d *msgpackDecoder) decodeMap(n int) (any, error) {
+	keys, values := make([]any, n), make([]any, n)
+	allStrings := true
+	for i := 0; i < n; i++ {
+		k, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		_, ok := k.(string)
+		keys[i], values[i], allStrings = k, v, allStrings && ok
+	}
+	if allStrings {
+		m := make(map[string]any, n)
+		for i, k := range keys {
+			m[k.(string)] = values[i]
+		}
+		return m, nil
+	}
+	m := make(map[any]any, n)
+	for i, k := range keys {
+		m[k] = values[i]
+	}
+	return m, nil
+}
+
+func (d *msgpackDecoder) decodeExt(n int) (any, error) {
+	typ, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	data, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	if int8(typ[0]) != -1 {
+		return nil, fmt.Errorf("unknown ext type %d", int8(typ[0]))
+	}
+	switch n {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(data)), 0).UTC(), nil
+	case 8:
+		u := binary.BigEndian.Uint64(data)
+		return time.Unix(int64(u&(1<<34-1)), int64(u>>34)).UTC(), nil
+	case 12:
+		return time.Unix(int64(binary.BigEndian.Uint64(data[4:])), int64(binary.BigEndian.Uint32(data))).UTC(), nil
+	}
+	return nil, fmt.Errorf("invalid timestamp length %d", n)
+}
+
+type blob [2]byte
+
+func (b blob) MarshalBinary() ([]byte, error) {
+	return []byte{b[1], b[0]}, nil
+}
+
+type Base struct {
+	ID int `json:"id"`
+}
+
+type meta struct {
+	Tag string
+}
+
+type Audit struct {
+	By string
+}
+
+type pointers struct {
+	Time  *time.Time
+	Int   *int
+	Nil   *string
+	Deep  **int
+	Blob  *blob
+	Iface any
+}
+
+type embedded struct {
+	Base
+	meta
+	*Audit
+	Named Base `msgpack:"named"`
+	Name  string
+}
+
+type omitted struct {
+	Int   int            `msgpack:"int,omitempty"`
+	Str   string         `msgpack:"str,omitempty"`
+	Slice []int          `msgpack:"slice,omitempty"`
+	Map   map[string]int `msgpack:"map,omitempty"`
+	Ptr   *int           `msgpack:"ptr,omitempty"`
+	Time  time.Time      `msgpack:"time,omitempty"`
+	Kept  int            `msgpack:"kept"`
+}
+
+func TestMsgPackRoundTrip(t *testing.T) {
+	day := time.Date(2024, 5, 1, 8, 30, 0, 123456789, time.UTC)
+	n := 7
+	np := &n
+	ints := func(n int) []any {
+		a := make([]any, n)
+		for i := range a {
+			a[i] = int64(i)
+		}
+		return a
+	}
+	intSlice := func(n int) []int {
+		a := make([]int, n)
+		for i := range a {
+			a[i] = i
+		}
+		return a
+	}
+	bigMap := func(n int) (map[string]int, map[string]any) {
+		m, want := make(map[string]int, n), make(map[string]any, n)
+		for i := 0; i < n; i++ {
+			k := "k" + strconv.Itoa(i)
+			m[k], want[k] = i, int64(i)
+		}
+		return m, want
+	}
+	map16, want16 := bigMap(16)
+	map32, want32 := bigMap(70000)
+	bytes32 := make([]byte, 70000)
+	bytes32[69999] = 9
+
+	tests := []struct {
+		name string
+		in   any
+		want any
+	}{
+		{"pointers", &pointers{Time: &day, Int: np, Deep: &np, Blob: &blob{1, 2}, Iface: &day}, map[string]any{
+			"Time": day, "Int": int64(7), "Nil": nil, "Deep": int64(7), "Blob": []byte{2, 1}, "Iface": day,
+		}},
+		{"nil pointer", (*pointers)(nil), nil},
+		{"embedded", embedded{Base: Base{ID: 1}, meta: meta{Tag: "t"}, Audit: &Audit{By: "amy"}, Named: Base{ID: 2}, Name: "tom"}, map[string]any{
+			"id": int64(1), "Tag": "t", "By": "amy", "named": map[string]any{"id": int64(2)}, "Name": "tom",
+		}},
+		{"nil embedded pointer", embedded{Name: "tom"}, map[string]any{
+			"id": int64(0), "Tag": "", "named": map[string]any{"id": int64(0)}, "Name": "tom",
+		}},
+		{"omitempty zero", omitted{}, map[string]any{"kept": int64(0)}},
+		{"omitempty set", omitted{Int: 1, Str: "s", Slice: []int{}, Map: map[string]int{"a": 1}, Ptr: new(int), Time: day}, map[string]any{
+			"int": int64(1), "str": "s", "slice": []any{}, "map": map[string]any{"a": int64(1)}, "ptr": int64(0), "time": day, "kept": int64(0),
+		}},
+		{"integers", []any{127, 128, 255, 256, 65535, 65536, uint64(math.MaxUint32) + 1, uint64(math.MaxUint64), -32, -33, -128, -129, -32768, -32769, math.MinInt32 - 1, int64(math.MinInt64)}, []any{
+			int64(127), int64(128), int64(255), int64(256), int64(65535), int64(65536), int64(math.MaxUint32) + 1, uint64(math.MaxUint64),
+			int64(-32), int64(-33), int64(-128), int64(-129), int64(-32768), int64(-32769), int64(math.MinInt32 - 1), int64(math.MinInt64),
+		}},
+		{"floats", []any{float32(1.5), 2.25}, []any{1.5, 2.25}},
+		{"array16", intSlice(16), ints(16)},
+		{"array32", intSlice(70000), ints(70000)},
+		{"map16", map16, want16},
+		{"map32", map32, want32},
+		{"strings", []string{strings.Repeat("a", 31), strings.Repeat("b", 32), strings.Repeat("c", 256), strings.Repeat("d", 70000)}, []any{
+			strings.Repeat("a", 31), strings.Repeat("b", 32), strings.Repeat("c", 256), strings.Repeat("d", 70000),
+		}},
+		{"bytes", [][]byte{make([]byte, 256), bytes32}, []any{make([]byte, 256), bytes32}},
+		{"int keys", map[int]string{1: "a"}, map[any]any{int64(1): "a"}},
+	}
+	for _, test := range tests {
+		data, err := MarshalMsgPack(test.in)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		got, err := decodeMsgPack(data)
+		if err != nil {
+			t.Fatalf("%s: decode: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Fatalf("%s: got %v, want %v", test.name, truncate(got), truncate(test.want))
+		}
+	}
+}
+
+func truncate(v any) string {
+	s := fmt.Sprint(v)
+	if len(s) > 200 {
+		return s[:200] + "..."
+	}
+	return s
+}
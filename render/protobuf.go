@@ -0,0 +1,30 @@
+package render
+
+import (
+	"errors"
+	"google.golang.org/protobuf/proto"
+	"net/http"
+)
+
+// ProtoBuf Data 必须实现 proto.Message
+type ProtoBuf struct {
+	Data any
+}
+
+func (r ProtoBuf) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	message, ok := r.Data.(proto.Message)
+	if !ok {
+		return errors.New("render: protobuf data must implement proto.Message")
+	}
+	bytes, err := proto.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(bytes)
+	return err
+}
+
+func (r ProtoBuf) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/x-protobuf")
+}
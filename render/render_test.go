@@ -0,0 +1,104 @@
+package render
+
+import (
+	"bytes"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type report struct {
+	ID      int       `csv:"id" msgpack:"id"`
+	Name    string    `csv:"name" json:"name"`
+	Day     time.Time `csv:"day" time_format:"2006-01-02" msgpack:"-"`
+	Score   *float64  `csv:"score" msgpack:"score,omitempty"`
+	Ignored string    `csv:"-" msgpack:"-"`
+}
+
+func TestMsgPack(t *testing.T) {
+	data, err := MarshalMsgPack(map[string]any{
+		"a": []any{nil, true, -1, -100, 200, 1.5, "hi", []byte{1}},
+		"b": report{ID: 1, Name: "tom"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		0x82,
+		0xa1, 'a', 0x98, 0xc0, 0xc3, 0xff, 0xd0, 0x9c, 0xcc, 0xc8, 0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0, 0xa2, 'h', 'i', 0xc4, 0x01, 0x01,
+		0xa1, 'b', 0x82, 0xa2, 'i', 'd', 0x01, 0xa4, 'n', 'a', 'm', 'e', 0xa3, 't', 'o', 'm',
+	}
+	if !bytes.Equal(data, want) {
+		t.Fatalf("data = % x", data)
+	}
+	if _, err := MarshalMsgPack(make(chan int)); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestCSV(t *testing.T) {
+	score := 9.5
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	w := httptest.NewRecorder()
+	err := CSV{Data: []*report{{ID: 1, Name: "tom, jr", Day: day, Score: &score}, {ID: 2, Name: "amy"}}, Filename: "report.csv"}.Render(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "id,name,day,score\n1,\"tom, jr\",2024-05-01,9.5\n2,amy,0001-01-01,\n"
+	if w.Body.String() != want || w.Header().Get("Content-Disposition") != "attachment; filename=report.csv" {
+		t.Fatalf("body = %q, header = %v", w.Body.String(), w.Header())
+	}
+
+	rows := make(chan []string)
+	go func() {
+		defer close(rows)
+		rows <- []string{"a", "b"}
+		rows <- []string{"c", "d"}
+	}()
+	w = httptest.NewRecorder()
+	if err := (CSV{Data: rows, Comma: ';'}).Render(w); err != nil {
+		t.Fatal(err)
+	}
+	if w.Body.String() != "a;b\nc;d\n" || !w.Flushed {
+		t.Fatalf("body = %q, flushed = %v", w.Body.String(), w.Flushed)
+	}
+
+	if err := (CSV{Data: []int{1}}).Render(httptest.NewRecorder()); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestEncodedFormats(t *testing.T) {
+	data := map[string]any{"name": "tom", "tags": []string{"a"}}
+	tests := []struct {
+		render      Render
+		contentType string
+		body        string
+	}{
+		{YAML{Data: data}, "application/yaml; charset=utf-8", "name: tom\ntags:\n    - a\n"},
+		{TOML{Data: data}, "application/toml; charset=utf-8", "name = \"tom\"\ntags = [\"a\"]\n"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		if err := test.render.Render(w); err != nil {
+			t.Fatal(err)
+		}
+		if w.Header().Get("Content-Type") != test.contentType || w.Body.String() != test.body {
+			t.Fatalf("%T: header = %v, body = %q", test.render, w.Header(), w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	if err := (ProtoBuf{Data: wrapperspb.String("tom")}).Render(w); err != nil {
+		t.Fatal(err)
+	}
+	var message wrapperspb.StringValue
+	if err := proto.Unmarshal(w.Body.Bytes(), &message); err != nil || message.Value != "tom" {
+		t.Fatalf("message = %v, err = %v", message.Value, err)
+	}
+	if err := (ProtoBuf{Data: data}).Render(httptest.NewRecorder()); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,88 @@
+package render
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// SSEvent Server-Sent Events 的一个事件
+type SSEvent struct {
+	Id    string
+	Event string
+	// Retry 客户端断线后的重连间隔，单位毫秒，0 表示不发送
+	Retry uint
+	// Data string 和 []byte 原样写入，其他类型编码为 JSON
+	Data any
+}
+
+func (r SSEvent) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return WriteSSEvent(w, r)
+}
+
+// WriteContentType 只在第一个事件时设置响应头
+func (r SSEvent) WriteContentType(w http.ResponseWriter) {
+	header := w.Header()
+	if header.Get("Content-Type") != "" {
+		return
+	}
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+}
+
+// WriteSSEvent 按 id、event、retry、data 的顺序写入一个事件，多行数据拆成多个 data 行
+func WriteSSEvent(w io.Writer, event SSEvent) error {
+	var b strings.Builder
+	if event.Id != "" {
+		b.WriteString("id: ")
+		b.WriteString(singleLine(event.Id))
+		b.WriteByte('\n')
+	}
+	if event.Event != "" {
+		b.WriteString("event: ")
+		b.WriteString(singleLine(event.Event))
+		b.WriteByte('\n')
+	}
+	if event.Retry > 0 {
+		b.WriteString("retry: ")
+		b.WriteString(strconv.FormatUint(uint64(event.Retry), 10))
+		b.WriteByte('\n')
+	}
+	data, err := sseData(event.Data)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: ")
+		b.WriteString(strings.TrimSuffix(line, "\r"))
+		b.WriteByte('\n')
+	}
+	b.WriteByte('\n')
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+func sseData(data any) (string, error) {
+	switch v := data.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	}
+	jsonBytes, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
+
+// singleLine id 和 event 中不能出现换行，否则会破坏事件格式
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
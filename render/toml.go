@@ -0,0 +1,26 @@
+package render
+
+import (
+	"bytes"
+	"github.com/BurntSushi/toml"
+	"net/http"
+)
+
+// TOML Data 必须是结构体或 map，TOML 文档的顶层只能是表
+type TOML struct {
+	Data any
+}
+
+func (r TOML) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(r.Data); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func (r TOML) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/toml; charset=utf-8")
+}
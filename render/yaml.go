@@ -0,0 +1,24 @@
+package render
+
+import (
+	"gopkg.in/yaml.v3"
+	"net/http"
+)
+
+type YAML struct {
+	Data any
+}
+
+func (r YAML) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	bytes, err := yaml.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(bytes)
+	return err
+}
+
+func (r YAML) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/yaml; charset=utf-8")
+}
@@ -0,0 +1,135 @@
+package go_framework
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+)
+
+const noWritten = -1
+
+// ResponseWriter 记录状态码、写入的字节数以及响应头是否已经发送，
+// WriteHeader 只记录状态码，第一次 Write 或调用 WriteHeaderNow 时才发送响应头
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Hijacker
+	http.Flusher
+	http.Pusher
+	io.StringWriter
+
+	// Status 返回响应的状态码
+	Status() int
+	// Size 返回已写入响应体的字节数，响应头未发送时为 -1
+	Size() int
+	// Written 响应头是否已经发送
+	Written() bool
+	// WriteHeaderNow 立即发送响应头
+	WriteHeaderNow()
+	// Unwrap 返回底层的 http.ResponseWriter，供 http.ResponseController 使用
+	Unwrap() http.ResponseWriter
+}
+
+var _ ResponseWriter = &responseWriter{}
+
+type responseWriter struct {
+	http.ResponseWriter
+	size        int
+	status      int
+	discardBody bool // 用 GET 的 handler 应答 HEAD 请求时丢弃响应体
+}
+
+// newResponseWriter 包装标准的 http.ResponseWriter，已经是 ResponseWriter 时直接返回
+func newResponseWriter(w http.ResponseWriter) ResponseWriter {
+	if rw, ok := w.(ResponseWriter); ok {
+		return rw
+	}
+	rw := &responseWriter{}
+	rw.reset(w)
+	return rw
+}
+
+func (w *responseWriter) reset(writer http.ResponseWriter) {
+	w.ResponseWriter = writer
+	w.size = noWritten
+	w.status = http.StatusOK
+	w.discardBody = false
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if code > 0 && w.status != code && !w.Written() {
+		w.status = code
+	}
+}
+
+func (w *responseWriter) WriteHeaderNow() {
+	if !w.Written() {
+		w.size = 0
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *responseWriter) Write(data []byte) (n int, err error) {
+	w.WriteHeaderNow()
+	if w.discardBody {
+		n = len(data)
+	} else {
+		n, err = w.ResponseWriter.Write(data)
+	}
+	w.size += n
+	return
+}
+
+func (w *responseWriter) WriteString(s string) (n int, err error) {
+	w.WriteHeaderNow()
+	if w.discardBody {
+		n = len(s)
+	} else {
+		n, err = io.WriteString(w.ResponseWriter, s)
+	}
+	w.size += n
+	return
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+func (w *responseWriter) Written() bool {
+	return w.size != noWritten
+}
+
+// Hijack 接管连接后响应由调用方负责，不再发送响应头
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not implement http.Hijacker")
+	}
+	if w.size < 0 {
+		w.size = 0
+	}
+	return hijacker.Hijack()
+}
+
+func (w *responseWriter) Flush() {
+	w.WriteHeaderNow()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := w.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,46 @@
+package go_framework
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestResponseWriterStatus(t *testing.T) {
+	engine := New()
+	var status, size int
+	engine.Use(func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			next(ctx)
+			status, size = ctx.W.Status(), ctx.W.Size()
+		}
+	})
+	g := engine.Group("")
+	g.Post("/json", func(ctx *Context) {
+		ctx.JSON(http.StatusCreated, map[string]int{"id": 1})
+	})
+	g.Get("/empty", func(ctx *Context) {
+		ctx.String(http.StatusNoContent, "ignored")
+	})
+	g.Get("/raw", func(ctx *Context) {
+		ctx.W.WriteHeader(http.StatusAccepted)
+		ctx.W.Write([]byte("raw"))
+		ctx.W.WriteHeader(http.StatusOK)
+	})
+
+	w := performRequest(engine, http.MethodPost, "/json")
+	if w.Code != http.StatusCreated || w.Body.String() != `{"id":1}` || status != http.StatusCreated || size != 8 {
+		t.Fatalf("code = %d, body = %q, status = %d, size = %d", w.Code, w.Body.String(), status, size)
+	}
+	w = performRequest(engine, http.MethodGet, "/empty")
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 || size != 0 {
+		t.Fatalf("code = %d, body = %q, size = %d", w.Code, w.Body.String(), size)
+	}
+	w = performRequest(engine, http.MethodGet, "/raw")
+	if w.Code != http.StatusAccepted || status != http.StatusAccepted || size != 3 {
+		t.Fatalf("code = %d, status = %d, size = %d", w.Code, status, size)
+	}
+	performRequest(engine, http.MethodGet, "/none")
+	if status != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", status)
+	}
+}
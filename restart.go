@@ -0,0 +1,124 @@
+package go_framework
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+const (
+	// listenerFdEnv 热重启时新进程通过该环境变量获取继承的 socket 文件描述符
+	listenerFdEnv = "GO_FRAMEWORK_LISTENER_FD"
+	// readyFdEnv 新进程开始接收连接后向该文件描述符写入一个字节，通知旧进程可以退出
+	readyFdEnv = "GO_FRAMEWORK_READY_FD"
+	// restartReadyTimeout 等待新进程就绪的最长时间
+	restartReadyTimeout = 30 * time.Second
+)
+
+// inheritedListener 返回热重启时从父进程继承的 Listener，不是热重启启动时返回 nil
+func inheritedListener() (net.Listener, error) {
+	value := os.Getenv(listenerFdEnv)
+	if value == "" {
+		return nil, nil
+	}
+	os.Unsetenv(listenerFdEnv)
+	fd, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s=%s", listenerFdEnv, value)
+	}
+	f := os.NewFile(uintptr(fd), "listener")
+	defer f.Close()
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, err
+	}
+	// FileListener 返回的 unix Listener 关闭时不删除 socket 文件，由新进程负责删除
+	if ul, ok := ln.(*net.UnixListener); ok {
+		ul.SetUnlinkOnClose(true)
+	}
+	return ln, nil
+}
+
+// notifyReady 热重启启动的新进程开始接收连接后通知旧进程
+func notifyReady() {
+	value := os.Getenv(readyFdEnv)
+	if value == "" {
+		return
+	}
+	os.Unsetenv(readyFdEnv)
+	fd, err := strconv.Atoi(value)
+	if err != nil {
+		return
+	}
+	f := os.NewFile(uintptr(fd), "ready")
+	f.Write([]byte{1})
+	f.Close()
+}
+
+// restart 以相同的参数启动新进程，把 ln 作为文件描述符 3 传给新进程，
+// 等新进程通过文件描述符 4 通知已经开始接收连接后才返回，新进程没有就绪时结束它并返回错误
+func (e *Engine) restart(ln net.Listener) error {
+	fl, ok := ln.(interface {
+		File() (*os.File, error)
+	})
+	if !ok {
+		return errors.New("listener does not support hot restart")
+	}
+	f, err := fl.File()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	path, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(path, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	ready, readyW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer ready.Close()
+	cmd.Env = append(os.Environ(), listenerFdEnv+"=3", readyFdEnv+"=4")
+	cmd.ExtraFiles = []*os.File{f, readyW}
+	err = cmd.Start()
+	// 关闭旧进程持有的写端，新进程退出时读取才会返回 EOF
+	readyW.Close()
+	if err != nil {
+		return err
+	}
+	if err := waitReady(ready, restartReadyTimeout); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return fmt.Errorf("new process %d is not ready: %w", cmd.Process.Pid, err)
+	}
+	// 回收新进程，旧进程还没有退出时新进程退出不会留下僵尸进程
+	go cmd.Wait()
+	// 旧进程关闭 Listener 时不能删除新进程正在使用的 unix socket 文件
+	if ul, ok := ln.(*net.UnixListener); ok {
+		ul.SetUnlinkOnClose(false)
+	}
+	e.Logger.Info(fmt.Sprintf("started new process %d", cmd.Process.Pid))
+	return nil
+}
+
+// waitReady 等待新进程写入就绪通知
+func waitReady(ready *os.File, timeout time.Duration) error {
+	ready.SetReadDeadline(time.Now().Add(timeout))
+	var b [1]byte
+	if _, err := ready.Read(b[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return errors.New("exited before ready")
+		}
+		return err
+	}
+	return nil
+}
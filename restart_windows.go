@@ -0,0 +1,10 @@
+//go:build windows
+
+package go_framework
+
+import (
+	"os"
+)
+
+// restartSignals windows 不支持热重启
+var restartSignals []os.Signal
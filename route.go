@@ -4,9 +4,12 @@ import (
 	"fmt"
 	golog "github.com/JUYAFEI/go-framework/log"
 	"github.com/JUYAFEI/go-framework/render"
+	"github.com/JUYAFEI/go-framework/websocket"
 	"html/template"
-	"log"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
 	"sync"
 )
 
@@ -14,94 +17,268 @@ const Any = "Any"
 
 type HandlerFunc func(ctx *Context)
 
-// MiddlewareFunc 定义中间件
+// MiddlewareFunc 定义中间件，next 执行后面的处理链，多次调用 next 时每次都会重新执行，可以用于重试
 type MiddlewareFunc func(handler HandlerFunc) HandlerFunc
 
+// handler 把中间件转换为处理链中的一环，中间件没有调用 next 时中止处理链
+func (m MiddlewareFunc) handler() HandlerFunc {
+	return func(ctx *Context) {
+		index := ctx.index
+		m(func(ctx *Context) {
+			// 从当前中间件之后开始，前一次执行到了链尾或者被中止都不影响
+			ctx.index = index
+			ctx.Next()
+		})(ctx)
+		if ctx.index == index {
+			ctx.Abort()
+		}
+	}
+}
+
+// HandlerMiddleware 把通过 ctx.Next 和 ctx.Abort 控制流程的 HandlerFunc 转换为 MiddlewareFunc，
+// h 没有调用 ctx.Next 也没有中止时，返回后继续执行后面的处理链
+func HandlerMiddleware(h HandlerFunc) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			index := ctx.index
+			h(ctx)
+			// h 调用过 ctx.Next 时后面的处理链已经执行过
+			if ctx.index == index {
+				next(ctx)
+			}
+		}
+	}
+}
+
 type Router struct {
 	groups []*RouterGroup
 	engine *Engine
 }
 
 func (r *Router) Group(name string) *RouterGroup {
-	g := &RouterGroup{
+	g := r.newGroup(name, joinPrefix("", name))
+	r.addGroup(g)
+	return g
+}
+
+func (r *Router) newGroup(name string, prefix string) *RouterGroup {
+	return &RouterGroup{
 		groupName:          name,
+		prefix:             prefix,
+		router:             r,
 		handlerMap:         make(map[string]map[string]HandlerFunc),
 		middlewaresFuncMap: make(map[string]map[string][]MiddlewareFunc),
 		handlerMethodMap:   make(map[string][]string),
 		treeNode:           &Tree{Name: "/", Children: make([]*Tree, 0)},
 	}
-	r.groups = append(r.groups, g)
+}
+
+// Host 创建按 Host 匹配的分组，如 {tenant}.example.com，捕获的值通过 ctx.HostParam 获取
+func (r *Router) Host(pattern string) *RouterGroup {
+	g := r.newGroup(pattern, "")
+	g.host = newHostPattern(pattern)
+	r.addGroup(g)
 	return g
 }
 
+// addGroup 按前缀长度从长到短保存分组，前缀相同时匹配条件多的在前，匹配时更具体的分组优先
+func (r *Router) addGroup(g *RouterGroup) {
+	i := len(r.groups)
+	for i > 0 && r.groups[i-1].less(g) {
+		i--
+	}
+	r.groups = append(r.groups, nil)
+	copy(r.groups[i+1:], r.groups[i:])
+	r.groups[i] = g
+}
+
 type RouterGroup struct {
 	groupName          string                                 // group name
+	prefix             string                                 // 包含父分组的完整前缀，如 /api/v1
+	parent             *RouterGroup                           // 父分组
+	router             *Router                                //
 	handlerMap         map[string]map[string]HandlerFunc      // handler map
 	middlewaresFuncMap map[string]map[string][]MiddlewareFunc // 中间件map
 	handlerMethodMap   map[string][]string                    // handler method map
 	treeNode           *Tree
 	middlewares        []MiddlewareFunc // 前置中间件
+	host               *hostPattern     // Host 匹配
+	predicates         []MatchFunc      // 请求匹配条件
+}
+
+// MatchFunc 分组的请求匹配条件
+type MatchFunc func(req *http.Request) bool
+
+// MatchHeader 请求头 key 等于 value 时匹配，如 MatchHeader("X-API-Version", "2")
+func MatchHeader(key string, value string) MatchFunc {
+	return func(req *http.Request) bool {
+		return req.Header.Get(key) == value
+	}
+}
+
+// Group 创建子分组，子分组继承父分组的前缀和中间件
+func (r *RouterGroup) Group(name string) *RouterGroup {
+	g := r.router.newGroup(name, joinPrefix(r.prefix, name))
+	g.parent = r
+	r.router.addGroup(g)
+	return g
+}
+
+// Match 创建前缀相同、只在 predicate 返回 true 时匹配的子分组
+func (r *RouterGroup) Match(predicate MatchFunc) *RouterGroup {
+	g := r.router.newGroup(r.groupName, r.prefix)
+	g.parent = r
+	g.predicates = append(g.predicates, predicate)
+	r.router.addGroup(g)
+	return g
 }
 
 func (r *RouterGroup) Use(middlewares ...MiddlewareFunc) {
 	r.middlewares = append(r.middlewares, middlewares...)
 }
 
-func (r *RouterGroup) methodHandle(name string, method string, h HandlerFunc, ctx *Context) {
-	// 通用中间件中间件
-	if r.middlewares != nil {
-		for _, middleware := range r.middlewares {
-			h = middleware(h)
+// less 分组 r 应排在 g 后面
+func (r *RouterGroup) less(g *RouterGroup) bool {
+	if len(r.prefix) != len(g.prefix) {
+		return len(r.prefix) < len(g.prefix)
+	}
+	return r.conditions() < g.conditions()
+}
+
+// conditions 分组及父分组的匹配条件数量
+func (r *RouterGroup) conditions() int {
+	n := 0
+	for g := r; g != nil; g = g.parent {
+		n += len(g.predicates)
+		if g.host != nil {
+			n++
 		}
 	}
-	// 路由中间件
-	middlewareFuncs := r.middlewaresFuncMap[name][method]
-	if middlewareFuncs != nil {
-		for _, middlewareFunc := range middlewareFuncs {
-			h = middlewareFunc(h)
+	return n
+}
+
+// matchRequest 检查分组及父分组的 Host 和请求匹配条件，Host 捕获的值保存到 ctx.HostParams
+func (r *RouterGroup) matchRequest(ctx *Context) bool {
+	ctx.HostParams = ctx.HostParams[:0]
+	for g := r; g != nil; g = g.parent {
+		if g.host != nil && !g.host.match(ctx.R.Host, &ctx.HostParams) {
+			return false
+		}
+		for _, predicate := range g.predicates {
+			if !predicate(ctx.R) {
+				return false
+			}
 		}
 	}
-	h(ctx)
+	return true
+}
+
+// matchPath 前缀从路径开头匹配，返回去掉前缀后的路由
+func (r *RouterGroup) matchPath(path string) (string, bool) {
+	if r.prefix == "" {
+		return path, true
+	}
+	if path == r.prefix {
+		return "", true
+	}
+	if strings.HasPrefix(path, r.prefix) && path[len(r.prefix)] == '/' {
+		return path[len(r.prefix):], true
+	}
+	return "", false
+}
+
+// methodHandle 把中间件和 handler 追加到处理链后执行，
+// 全局中间件多次调用 next 时先去掉上一次追加的部分
+func (r *RouterGroup) methodHandle(name string, method string, h HandlerFunc, ctx *Context) {
+	ctx.handlers = ctx.handlers[:ctx.index+1]
+	for _, middleware := range r.combineMiddlewares(name, method) {
+		ctx.handlers = append(ctx.handlers, middleware.handler())
+	}
+	ctx.handlers = append(ctx.handlers, h)
+	ctx.Next()
+}
+
+// combineMiddlewares 执行顺序：父分组中间件 -> 子分组中间件 -> 路由中间件，
+// 同一级的中间件按注册顺序执行
+func (r *RouterGroup) combineMiddlewares(name string, method string) []MiddlewareFunc {
+	var groups []*RouterGroup
+	for g := r; g != nil; g = g.parent {
+		groups = append(groups, g)
+	}
+	var middlewares []MiddlewareFunc
+	for i := len(groups) - 1; i >= 0; i-- {
+		middlewares = append(middlewares, groups[i].middlewares...)
+	}
+	// 路由中间件
+	return append(middlewares, r.middlewaresFuncMap[name][method]...)
 }
 
-func (r *RouterGroup) handle(name string, method string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
+// Route 注册的路由
+type Route struct {
+	group        *RouterGroup
+	relativePath string // 分组内的路由
+	handler      HandlerFunc
+	Method       string
+	Path         string // 包含分组前缀的完整路由
+}
+
+// Name 给路由命名，用于 Engine.URLFor 和模板函数 urlfor 生成 URL，名称重复时 panic
+func (r *Route) Name(name string) *Route {
+	e := r.group.router.engine
+	if _, ok := e.namedRoutes[name]; ok {
+		panic(fmt.Sprintf("route name %s is already registered", name))
+	}
+	e.namedRoutes[name] = r
+	return r
+}
+
+func (r *RouterGroup) handle(name string, method string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) *Route {
+	if name == "" || name[0] != '/' {
+		name = "/" + name
+	}
 	_, ok := r.handlerMap[name]
 	if !ok {
 		r.handlerMap[name] = make(map[string]HandlerFunc)
 		r.middlewaresFuncMap[name] = make(map[string][]MiddlewareFunc)
 	}
+	if _, exist := r.handlerMap[name][method]; exist {
+		panic(fmt.Sprintf("%s %s is already registered in group %s", method, name, r.groupName))
+	}
 	r.handlerMap[name][method] = handlerFunc
 	r.handlerMethodMap[method] = append(r.handlerMethodMap[method], name)
 	r.middlewaresFuncMap[name][method] = append(r.middlewaresFuncMap[name][method], middlewareFunc...)
 	r.treeNode.Put(name)
+	route := &Route{group: r, relativePath: name, handler: handlerFunc, Method: method, Path: r.prefix + name}
+	r.router.engine.routes = append(r.router.engine.routes, route)
+	return route
 }
 
-func (r *RouterGroup) Any(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, Any, handlerFunc, middlewareFunc...)
+func (r *RouterGroup) Any(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) *Route {
+	return r.handle(name, Any, handlerFunc, middlewareFunc...)
 }
 
-func (r *RouterGroup) Get(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, http.MethodGet, handlerFunc, middlewareFunc...)
+func (r *RouterGroup) Get(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) *Route {
+	return r.handle(name, http.MethodGet, handlerFunc, middlewareFunc...)
 }
 
-func (r *RouterGroup) Post(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, http.MethodPost, handlerFunc, middlewareFunc...)
+func (r *RouterGroup) Post(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) *Route {
+	return r.handle(name, http.MethodPost, handlerFunc, middlewareFunc...)
 }
 
-func (r *RouterGroup) Delete(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, http.MethodDelete, handlerFunc, middlewareFunc...)
+func (r *RouterGroup) Delete(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) *Route {
+	return r.handle(name, http.MethodDelete, handlerFunc, middlewareFunc...)
 }
-func (r *RouterGroup) Put(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, http.MethodPut, handlerFunc, middlewareFunc...)
+func (r *RouterGroup) Put(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) *Route {
+	return r.handle(name, http.MethodPut, handlerFunc, middlewareFunc...)
 }
-func (r *RouterGroup) Patch(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, http.MethodPatch, handlerFunc, middlewareFunc...)
+func (r *RouterGroup) Patch(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) *Route {
+	return r.handle(name, http.MethodPatch, handlerFunc, middlewareFunc...)
 }
-func (r *RouterGroup) Options(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, http.MethodOptions, handlerFunc, middlewareFunc...)
+func (r *RouterGroup) Options(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) *Route {
+	return r.handle(name, http.MethodOptions, handlerFunc, middlewareFunc...)
 }
-func (r *RouterGroup) Head(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, http.MethodHead, handlerFunc, middlewareFunc...)
+func (r *RouterGroup) Head(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) *Route {
+	return r.handle(name, http.MethodHead, handlerFunc, middlewareFunc...)
 }
 
 type Engine struct {
@@ -111,38 +288,83 @@ type Engine struct {
 	pool       sync.Pool
 	Logger     *golog.Logger
 	middles    []MiddlewareFunc
+	noRoute    HandlerFunc
+	noMethod   HandlerFunc
+	// routes 按注册顺序保存的路由
+	routes []*Route
+	// namedRoutes 命名路由
+	namedRoutes map[string]*Route
+	running     *runningServer
+	serverLock  sync.Mutex
+	// HandleMethodNotAllowed 路由存在但请求方法不匹配时返回 405，为 false 时返回 404
+	HandleMethodNotAllowed bool
+	// HandleHead 路由没有注册 HEAD 时使用 GET 的 handler 应答，并丢弃响应体
+	HandleHead bool
+	// HandleOptions 路由没有注册 OPTIONS 时返回 204 和 Allow 响应头
+	HandleOptions bool
+	// RedirectTrailingSlash 路由不存在但去掉或加上末尾的 / 后存在时重定向过去
+	RedirectTrailingSlash bool
+	// RedirectFixedPath 路由不存在时清理路径中多余的 / 和 ..，并忽略大小写查找，找到后重定向过去
+	RedirectFixedPath bool
+	// UseRawPath 使用 URL.RawPath 匹配路由，路由参数中可以包含编码后的 /
+	UseRawPath bool
+	// UnescapePathValues UseRawPath 为 true 时对路由参数进行解码
+	UnescapePathValues bool
+	// WebSocketUpgrader RouterGroup.WebSocket 握手使用的配置
+	WebSocketUpgrader websocket.Upgrader
 }
 
 func (e *Engine) SetFuncMap(funcMap template.FuncMap) {
 	e.funcMap = funcMap
 }
 
+// FuncMap 返回 SetFuncMap 设置的模板函数和 urlfor，自己创建模板再调用 SetHtmlTemplate 时使用
+func (e *Engine) FuncMap() template.FuncMap {
+	funcMap := template.FuncMap{"urlfor": e.URLFor}
+	for name, f := range e.funcMap {
+		funcMap[name] = f
+	}
+	return funcMap
+}
+
 func (e *Engine) SetHtmlTemplate(t *template.Template) {
 	e.HTMLRender = render.HTMLRender{Template: t}
 }
 
+// LoadTemplateGlob 加载模板，模板中可以使用 urlfor 生成命名路由的 URL
 func (e *Engine) LoadTemplateGlob(pattern string) {
-	t := template.Must(template.New("").Funcs(e.funcMap).ParseGlob(pattern))
+	t := template.Must(template.New("").Funcs(e.FuncMap()).ParseGlob(pattern))
 	e.SetHtmlTemplate(t)
 }
 
 func (e *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	ctx := e.pool.Get().(*Context)
-	ctx.W = w
+	ctx.writermem.reset(w)
+	ctx.W = &ctx.writermem
 	ctx.R = req
 	ctx.Logger = e.Logger
-	e.httpRequestHandler(ctx)
+	e.handle(ctx)
+	ctx.writermem.WriteHeaderNow()
+	ctx.reset()
 	e.pool.Put(ctx)
 }
 
 func New() *Engine {
 
 	engine := &Engine{
-		Router:     &Router{},
-		funcMap:    nil,
-		HTMLRender: render.HTMLRender{},
-		Logger:     golog.DefaultLogger(),
+		Router:      &Router{},
+		funcMap:     nil,
+		HTMLRender:  render.HTMLRender{},
+		Logger:      golog.DefaultLogger(),
+		noRoute:     defaultNoRoute,
+		noMethod:    defaultNoMethod,
+		namedRoutes: make(map[string]*Route),
+
+		HandleMethodNotAllowed: true,
+		RedirectTrailingSlash:  true,
+		UnescapePathValues:     true,
 	}
+	engine.Router.engine = engine
 	engine.pool.New = func() any {
 		return engine.allocateContext()
 	}
@@ -152,48 +374,266 @@ func New() *Engine {
 func Default() *Engine {
 	engine := New()
 	engine.Use(Logging, Recovery)
-	engine.Router.engine = engine
 	return engine
 }
 
 func (e *Engine) allocateContext() *Context {
-	return &Context{Engine: e}
+	return &Context{Engine: e, index: -1}
 }
 
+// Use 注册全局中间件，作用于所有请求，包括 404 和 405，按注册顺序执行
 func (e *Engine) Use(middles ...MiddlewareFunc) {
 	e.middles = append(e.middles, middles...)
 }
 
+// handle 全局中间件包裹路由查找和执行
+func (e *Engine) handle(ctx *Context) {
+	ctx.handlers = ctx.handlers[:ctx.index+1]
+	for _, middle := range e.middles {
+		ctx.handlers = append(ctx.handlers, middle.handler())
+	}
+	ctx.handlers = append(ctx.handlers, e.httpRequestHandler)
+	ctx.Next()
+}
+
 func (e *Engine) httpRequestHandler(ctx *Context) {
-	groups := e.Router.groups
-	for _, g := range groups {
-		routerName := SubStringLast(ctx.R.URL.Path, "/"+g.groupName)
-		node := g.treeNode.Get(routerName)
-		if node != nil {
-			anyHandler, ok := g.handlerMap[node.RouterName][Any]
+	method := ctx.R.Method
+	path := ctx.R.URL.Path
+	unescape := false
+	if e.UseRawPath && ctx.R.URL.RawPath != "" {
+		path = ctx.R.URL.RawPath
+		unescape = e.UnescapePathValues
+	}
+	var allow []string
+	for _, g := range e.Router.groups {
+		routerName, ok := g.matchPath(path)
+		if !ok || !g.matchRequest(ctx) {
+			continue
+		}
+		ctx.Params = ctx.Params[:0]
+		node := g.treeNode.Search(routerName, &ctx.Params)
+		if node == nil {
+			continue
+		}
+		if unescape {
+			for i, param := range ctx.Params {
+				if value, err := url.PathUnescape(param.Value); err == nil {
+					ctx.Params[i].Value = value
+				}
+			}
+		}
+		anyHandler, ok := g.handlerMap[node.RouterName][Any]
+		if ok {
+			g.methodHandle(node.RouterName, Any, anyHandler, ctx)
+			return
+		}
+		handler, ok := g.handlerMap[node.RouterName][method]
+		if ok {
+			g.methodHandle(node.RouterName, method, handler, ctx)
+			return
+		}
+		if method == http.MethodHead && e.HandleHead {
+			handler, ok = g.handlerMap[node.RouterName][http.MethodGet]
 			if ok {
-				g.methodHandle(node.RouterName, Any, anyHandler, ctx)
+				ctx.writermem.discardBody = true
+				g.methodHandle(node.RouterName, http.MethodGet, handler, ctx)
 				return
 			}
-			method := ctx.R.Method
-			handler, ok := g.handlerMap[node.RouterName][method]
-			if ok {
-				g.methodHandle(node.RouterName, method, handler, ctx)
+		}
+		if allow == nil {
+			allow = e.allowHeader(g.allowedMethods(node.RouterName))
+		}
+	}
+	ctx.Params = ctx.Params[:0]
+	ctx.HostParams = ctx.HostParams[:0]
+	if allow == nil && method != http.MethodConnect && path != "/" {
+		if e.RedirectTrailingSlash {
+			tsrPath := path + "/"
+			if strings.HasSuffix(path, "/") {
+				tsrPath = path[:len(path)-1]
+			}
+			if e.hasRoute(ctx, tsrPath) {
+				e.redirectPath(ctx, tsrPath, path != ctx.R.URL.Path)
 				return
 			}
-			ctx.W.WriteHeader(http.StatusMethodNotAllowed)
-			fmt.Fprintln(ctx.W, ctx.R.RequestURI+method+" not allowed")
-			return
 		}
+		if e.RedirectFixedPath {
+			if fixedPath, ok := e.findFixedPath(ctx, cleanPath(path)); ok && fixedPath != path {
+				e.redirectPath(ctx, fixedPath, path != ctx.R.URL.Path)
+				return
+			}
+		}
+	}
+	if allow != nil && method == http.MethodOptions && e.HandleOptions {
+		ctx.W.Header().Set("Allow", strings.Join(allow, ", "))
+		ctx.StatusCode = http.StatusNoContent
+		ctx.W.WriteHeader(http.StatusNoContent)
+		ctx.W.WriteHeaderNow()
+		return
 	}
+	if allow != nil && e.HandleMethodNotAllowed {
+		ctx.W.Header().Set("Allow", strings.Join(allow, ", "))
+		ctx.StatusCode = http.StatusMethodNotAllowed
+		ctx.W.WriteHeader(http.StatusMethodNotAllowed)
+		e.noMethod(ctx)
+		return
+	}
+	ctx.StatusCode = http.StatusNotFound
 	ctx.W.WriteHeader(http.StatusNotFound)
+	e.noRoute(ctx)
+}
+
+// allowedMethods 路由注册过的请求方法，用于 Allow 响应头
+func (r *RouterGroup) allowedMethods(name string) []string {
+	var methods []string
+	for method, names := range r.handlerMethodMap {
+		if method == Any {
+			continue
+		}
+		for _, n := range names {
+			if n == name {
+				methods = append(methods, method)
+				break
+			}
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+func (e *Engine) hasRoute(ctx *Context, path string) bool {
+	defer func() {
+		ctx.HostParams = ctx.HostParams[:0]
+	}()
+	for _, g := range e.Router.groups {
+		if routerName, ok := g.matchPath(path); ok && g.matchRequest(ctx) && g.treeNode.Get(routerName) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// findFixedPath 忽略大小写查找路由，返回注册时的写法
+func (e *Engine) findFixedPath(ctx *Context, path string) (string, bool) {
+	defer func() {
+		ctx.HostParams = ctx.HostParams[:0]
+	}()
+	for _, g := range e.Router.groups {
+		if !g.matchRequest(ctx) {
+			continue
+		}
+		prefix := g.prefix
+		if len(path) < len(prefix) || !strings.EqualFold(path[:len(prefix)], prefix) {
+			continue
+		}
+		if len(path) > len(prefix) && path[len(prefix)] != '/' {
+			continue
+		}
+		if fixed, ok := g.treeNode.FindCaseInsensitive(path[len(prefix):], e.RedirectTrailingSlash); ok {
+			return prefix + fixed, true
+		}
+	}
+	return "", false
+}
+
+// redirectPath GET 和 HEAD 请求返回 301，其他请求返回 308 以保留请求方法和请求体
+func (e *Engine) redirectPath(ctx *Context, path string, escaped bool) {
+	code := http.StatusMovedPermanently
+	if ctx.R.Method != http.MethodGet && ctx.R.Method != http.MethodHead {
+		code = http.StatusPermanentRedirect
+	}
+	location := path
+	if !escaped {
+		location = (&url.URL{Path: path}).EscapedPath()
+	}
+	if ctx.R.URL.RawQuery != "" {
+		location += "?" + ctx.R.URL.RawQuery
+	}
+	ctx.StatusCode = code
+	http.Redirect(ctx.W, ctx.R, location, code)
+}
+
+// allowHeader 加上自动应答的 HEAD 和 OPTIONS
+func (e *Engine) allowHeader(methods []string) []string {
+	hasGet, hasHead, hasOptions := false, false, false
+	for _, method := range methods {
+		switch method {
+		case http.MethodGet:
+			hasGet = true
+		case http.MethodHead:
+			hasHead = true
+		case http.MethodOptions:
+			hasOptions = true
+		}
+	}
+	if e.HandleHead && hasGet && !hasHead {
+		methods = append(methods, http.MethodHead)
+	}
+	if e.HandleOptions && !hasOptions {
+		methods = append(methods, http.MethodOptions)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+func defaultNoRoute(ctx *Context) {
 	fmt.Fprintf(ctx.W, "%s  not found \n", ctx.R.RequestURI)
 }
 
-func (e *Engine) Run() {
-	http.Handle("/", e)
-	err := http.ListenAndServe(":8111", nil)
-	if err != nil {
-		log.Fatal(err)
+func defaultNoMethod(ctx *Context) {
+	fmt.Fprintln(ctx.W, ctx.R.RequestURI+ctx.R.Method+" not allowed")
+}
+
+// NoRoute 设置路由不存在时的 handler，执行前状态码已设置为 404，会经过全局中间件
+func (e *Engine) NoRoute(handler HandlerFunc) {
+	e.noRoute = handler
+}
+
+// NoMethod 设置路由存在但请求方法不匹配时的 handler，执行前已设置 Allow 响应头和 405 状态码，
+// 会经过全局中间件
+func (e *Engine) NoMethod(handler HandlerFunc) {
+	e.noMethod = handler
+}
+
+// RouteInfo 路由信息，Middlewares 按执行顺序排列，包含全局中间件
+type RouteInfo struct {
+	Method      string   `json:"method"`
+	Path        string   `json:"path"`
+	Handler     string   `json:"handler"`
+	Middlewares []string `json:"middlewares"`
+}
+
+// Routes 返回所有注册的路由
+func (e *Engine) Routes() []RouteInfo {
+	routes := make([]RouteInfo, 0, len(e.routes))
+	for _, route := range e.routes {
+		middlewares := append([]MiddlewareFunc{}, e.middles...)
+		middlewares = append(middlewares, route.group.combineMiddlewares(route.relativePath, route.Method)...)
+		names := make([]string, 0, len(middlewares))
+		for _, middleware := range middlewares {
+			names = append(names, nameOfFunction(middleware))
+		}
+		routes = append(routes, RouteInfo{
+			Method:      route.Method,
+			Path:        route.Path,
+			Handler:     nameOfFunction(route.handler),
+			Middlewares: names,
+		})
+	}
+	return routes
+}
+
+// RoutesHandler 以 JSON 返回路由表，需要时自行注册，如 g.Get("/routes", engine.RoutesHandler())
+func (e *Engine) RoutesHandler() HandlerFunc {
+	return func(ctx *Context) {
+		ctx.JSON(http.StatusOK, e.Routes())
+	}
+}
+
+// debugPrintRoutes 启动时以 debug 级别打印路由表
+func (e *Engine) debugPrintRoutes() {
+	for _, route := range e.Routes() {
+		e.Logger.Debug(fmt.Sprintf("%-7s %-30s --> %s (%d middlewares)",
+			route.Method, route.Path, route.Handler, len(route.Middlewares)))
 	}
 }
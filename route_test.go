@@ -0,0 +1,626 @@
+package go_framework
+
+import (
+	"context"
+	"fmt"
+	"github.com/JUYAFEI/go-framework/render"
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	_ "net/http/pprof"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func performRequest(e *Engine, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	return w
+}
+
+func orderMiddleware(name string, order *[]string) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			*order = append(*order, name)
+			next(ctx)
+		}
+	}
+}
+
+func TestNestedGroup(t *testing.T) {
+	var order []string
+	engine := New()
+	api := engine.Group("api")
+	api.Use(orderMiddleware("api", &order))
+	v1 := api.Group("v1")
+	v1.Use(orderMiddleware("v1", &order))
+	admin := v1.Group("/admin/")
+	admin.Use(orderMiddleware("admin1", &order), orderMiddleware("admin2", &order))
+	admin.Get("/user/:id", func(ctx *Context) {
+		order = append(order, "handler")
+		ctx.String(http.StatusOK, ctx.Param("id"))
+	}, orderMiddleware("route", &order))
+	api.Get("/ping", func(ctx *Context) {
+		ctx.String(http.StatusOK, "pong")
+	})
+
+	w := performRequest(engine, http.MethodGet, "/api/v1/admin/user/7")
+	if w.Body.String() != "7" {
+		t.Fatalf("body = %q", w.Body.String())
+	}
+	want := []string{"api", "v1", "admin1", "admin2", "route", "handler"}
+	if len(order) != len(want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+
+	if w := performRequest(engine, http.MethodGet, "/api/ping"); w.Body.String() != "pong" {
+		t.Fatalf("body = %q", w.Body.String())
+	}
+	if w := performRequest(engine, http.MethodGet, "/x/api/ping"); w.Code != http.StatusNotFound {
+		t.Fatalf("code = %d, want 404", w.Code)
+	}
+	if w := performRequest(engine, http.MethodGet, "/apiping"); w.Code != http.StatusNotFound {
+		t.Fatalf("code = %d, want 404", w.Code)
+	}
+}
+
+func TestEngineMiddleware(t *testing.T) {
+	var order []string
+	engine := Default()
+	engine.Use(orderMiddleware("engine", &order))
+	g := engine.Group("user")
+	g.Use(orderMiddleware("group", &order))
+	g.Get("/panic", func(ctx *Context) {
+		panic("boom")
+	})
+
+	w := performRequest(engine, http.MethodGet, "/user/panic")
+	if w.Code != http.StatusInternalServerError || w.Body.String() != "Internal Server Error" {
+		t.Fatalf("code = %d, body = %q", w.Code, w.Body.String())
+	}
+	if len(order) != 2 || order[0] != "engine" || order[1] != "group" {
+		t.Fatalf("order = %v", order)
+	}
+
+	order = order[:0]
+	if w := performRequest(engine, http.MethodGet, "/missing"); w.Code != http.StatusNotFound {
+		t.Fatalf("code = %d, want 404", w.Code)
+	}
+	if len(order) != 1 || order[0] != "engine" {
+		t.Fatalf("order = %v", order)
+	}
+}
+
+func TestAbort(t *testing.T) {
+	engine := New()
+	g := engine.Group("user")
+	var reached, aborted bool
+	g.Use(func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			next(ctx)
+			aborted = ctx.IsAborted()
+		}
+	})
+	g.Use(HandlerMiddleware(func(ctx *Context) {
+		if ctx.GetQuery("token") == "" {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+		}
+	}))
+	g.Get("/info", func(ctx *Context) {
+		reached = true
+	})
+
+	w := performRequest(engine, http.MethodGet, "/user/info")
+	if w.Code != http.StatusUnauthorized || reached || !aborted {
+		t.Fatalf("code = %d, reached = %v, aborted = %v", w.Code, reached, aborted)
+	}
+
+	performRequest(engine, http.MethodGet, "/user/info?token=1")
+	if !reached || aborted {
+		t.Fatalf("reached = %v, aborted = %v", reached, aborted)
+	}
+}
+
+func TestMiddlewareWithoutNext(t *testing.T) {
+	engine := New()
+	g := engine.Group("user")
+	var reached bool
+	g.Get("/info", func(ctx *Context) {
+		reached = true
+	}, func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			ctx.W.WriteHeader(http.StatusForbidden)
+		}
+	})
+	w := performRequest(engine, http.MethodGet, "/user/info")
+	if w.Code != http.StatusForbidden || reached {
+		t.Fatalf("code = %d, reached = %v", w.Code, reached)
+	}
+}
+
+func TestMiddlewareNextTwice(t *testing.T) {
+	engine := New()
+	g := engine.Group("user")
+	var order []string
+	g.Use(func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			// 后面的处理链中止时重试一次
+			next(ctx)
+			if ctx.IsAborted() {
+				next(ctx)
+			}
+		}
+	})
+	g.Use(orderMiddleware("group", &order))
+	g.Get("/info", func(ctx *Context) {
+		order = append(order, "handler")
+		if len(order) == 2 {
+			ctx.Abort()
+			return
+		}
+		ctx.String(http.StatusOK, "ok")
+	})
+	w := performRequest(engine, http.MethodGet, "/user/info")
+	if strings.Join(order, ",") != "group,handler,group,handler" || w.Body.String() != "ok" {
+		t.Fatalf("order = %v, body = %q", order, w.Body.String())
+	}
+
+	// 全局中间件重试时，分组中间件和 handler 不会重复追加到处理链
+	engine = New()
+	engine.Use(func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			next(ctx)
+			next(ctx)
+		}
+	})
+	order = nil
+	g = engine.Group("user")
+	g.Use(orderMiddleware("group", &order))
+	g.Get("/info", func(ctx *Context) {
+		order = append(order, "handler")
+	})
+	performRequest(engine, http.MethodGet, "/user/info")
+	if strings.Join(order, ",") != "group,handler,group,handler" {
+		t.Fatalf("order = %v", order)
+	}
+}
+
+func TestHandlerMiddlewareNext(t *testing.T) {
+	engine := New()
+	g := engine.Group("user")
+	var order []string
+	g.Use(HandlerMiddleware(func(ctx *Context) {
+		order = append(order, "before")
+		ctx.Next()
+		order = append(order, "after")
+	}))
+	g.Use(HandlerMiddleware(func(ctx *Context) {
+		order = append(order, "plain")
+	}))
+	g.Get("/info", func(ctx *Context) {
+		order = append(order, "handler")
+	})
+	performRequest(engine, http.MethodGet, "/user/info")
+	if strings.Join(order, ",") != "before,plain,handler,after" {
+		t.Fatalf("order = %v", order)
+	}
+}
+
+func TestNoRouteNoMethod(t *testing.T) {
+	engine := New()
+	g := engine.Group("user")
+	g.Get("/info", func(ctx *Context) {})
+	g.Post("/info", func(ctx *Context) {})
+	g.Delete("/info", func(ctx *Context) {})
+
+	w := performRequest(engine, http.MethodPut, "/user/info")
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("code = %d, want 405", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "DELETE, GET, POST" {
+		t.Fatalf("Allow = %q", allow)
+	}
+
+	engine.NoRoute(func(ctx *Context) {
+		ctx.W.WriteHeader(ctx.StatusCode)
+		ctx.W.Write([]byte("custom 404"))
+	})
+	engine.NoMethod(func(ctx *Context) {
+		ctx.W.WriteHeader(ctx.StatusCode)
+		ctx.W.Write([]byte("custom 405"))
+	})
+	if w := performRequest(engine, http.MethodGet, "/none"); w.Code != http.StatusNotFound || w.Body.String() != "custom 404" {
+		t.Fatalf("code = %d, body = %q", w.Code, w.Body.String())
+	}
+	if w := performRequest(engine, http.MethodPut, "/user/info"); w.Code != http.StatusMethodNotAllowed || w.Body.String() != "custom 405" {
+		t.Fatalf("code = %d, body = %q", w.Code, w.Body.String())
+	}
+
+	engine.HandleMethodNotAllowed = false
+	if w := performRequest(engine, http.MethodPut, "/user/info"); w.Code != http.StatusNotFound {
+		t.Fatalf("code = %d, want 404", w.Code)
+	}
+}
+
+func TestAutoHeadOptions(t *testing.T) {
+	engine := New()
+	g := engine.Group("")
+	g.Get("/health", func(ctx *Context) {
+		ctx.W.Header().Set("X-Health", "ok")
+		ctx.W.Write([]byte("ok"))
+	})
+	g.Post("/health", func(ctx *Context) {})
+
+	if w := performRequest(engine, http.MethodHead, "/health"); w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("code = %d, want 405", w.Code)
+	}
+
+	engine.HandleHead = true
+	engine.HandleOptions = true
+	w := performRequest(engine, http.MethodHead, "/health")
+	if w.Code != http.StatusOK || w.Body.Len() != 0 || w.Header().Get("X-Health") != "ok" {
+		t.Fatalf("code = %d, body = %q", w.Code, w.Body.String())
+	}
+	w = performRequest(engine, http.MethodOptions, "/health")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("code = %d, want 204", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS, POST" {
+		t.Fatalf("Allow = %q", allow)
+	}
+	if w := performRequest(engine, http.MethodOptions, "/none"); w.Code != http.StatusNotFound {
+		t.Fatalf("code = %d, want 404", w.Code)
+	}
+}
+
+func TestRedirectPath(t *testing.T) {
+	engine := New()
+	g := engine.Group("user")
+	g.Get("/info", func(ctx *Context) {})
+	g.Post("/list/", func(ctx *Context) {})
+	g.Get("/file/:name", func(ctx *Context) {
+		ctx.W.Write([]byte(ctx.Param("name")))
+	})
+
+	tests := []struct {
+		method   string
+		path     string
+		code     int
+		location string
+	}{
+		{http.MethodGet, "/user/info/", http.StatusMovedPermanently, "/user/info"},
+		{http.MethodHead, "/user/info/", http.StatusMovedPermanently, "/user/info"},
+		{http.MethodPost, "/user/list", http.StatusPermanentRedirect, "/user/list/"},
+		{http.MethodGet, "/user/info/?a=1", http.StatusMovedPermanently, "/user/info?a=1"},
+		{http.MethodGet, "/USER//Info", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		w := performRequest(engine, test.method, test.path)
+		if w.Code != test.code || w.Header().Get("Location") != test.location {
+			t.Fatalf("%s %s: code = %d, location = %q", test.method, test.path, w.Code, w.Header().Get("Location"))
+		}
+	}
+
+	engine.RedirectFixedPath = true
+	w := performRequest(engine, http.MethodGet, "/USER//Info")
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/user/info" {
+		t.Fatalf("code = %d, location = %q", w.Code, w.Header().Get("Location"))
+	}
+	w = performRequest(engine, http.MethodGet, "/User/File/A.txt/")
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/user/file/A.txt" {
+		t.Fatalf("code = %d, location = %q", w.Code, w.Header().Get("Location"))
+	}
+
+	engine.UseRawPath = true
+	if w := performRequest(engine, http.MethodGet, "/user/file/a%2Fb"); w.Body.String() != "a/b" {
+		t.Fatalf("body = %q", w.Body.String())
+	}
+	engine.UnescapePathValues = false
+	if w := performRequest(engine, http.MethodGet, "/user/file/a%2Fb"); w.Body.String() != "a%2Fb" {
+		t.Fatalf("body = %q", w.Body.String())
+	}
+}
+
+func TestURLFor(t *testing.T) {
+	engine := New()
+	g := engine.Group("api").Group("v1")
+	g.Get("/user/:id<int>/posts/:slug", func(ctx *Context) {}).Name("user.post")
+	g.Get("/static/*filepath", func(ctx *Context) {}).Name("static")
+
+	tests := []struct {
+		name   string
+		params []any
+		url    string
+		err    bool
+	}{
+		{"user.post", []any{"id", 7, "slug", "hello world"}, "/api/v1/user/7/posts/hello%20world", false},
+		{"user.post", []any{"id", 7, "slug", "a", "page", 2}, "/api/v1/user/7/posts/a?page=2", false},
+		{"user.post", []any{"id", "x", "slug", "a"}, "", true},
+		{"user.post", []any{"id", 7}, "", true},
+		{"static", []any{"filepath", "css/app.css"}, "/api/v1/static/css/app.css", false},
+		{"missing", nil, "", true},
+	}
+	for _, test := range tests {
+		u, err := engine.URLFor(test.name, test.params...)
+		if (err != nil) != test.err || u != test.url {
+			t.Fatalf("%s %v: url = %q, err = %v", test.name, test.params, u, err)
+		}
+	}
+
+	// 自己创建的模板和 HTMLTemplateGlob 都可以使用 urlfor
+	engine.SetHtmlTemplate(template.Must(template.New("link").Funcs(engine.FuncMap()).Parse(`{{urlfor "static" "filepath" "app.js"}}`)))
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "glob.html"), []byte(`{{urlfor "user.post" "id" 1 "slug" "a"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	g.Get("/link", func(ctx *Context) {
+		ctx.HTMLTemplate("link", nil)
+	})
+	g.Get("/glob", func(ctx *Context) {
+		ctx.HTMLTemplateGlob("glob.html", nil, filepath.Join(dir, "*.html"), nil)
+	})
+	if w := performRequest(engine, http.MethodGet, "/api/v1/link"); w.Body.String() != "/api/v1/static/app.js" {
+		t.Fatalf("body = %q", w.Body.String())
+	}
+	if w := performRequest(engine, http.MethodGet, "/api/v1/glob"); w.Body.String() != "/api/v1/user/1/posts/a" {
+		t.Fatalf("body = %q", w.Body.String())
+	}
+}
+
+func routeInfoHandler(ctx *Context) {}
+
+func TestRoutes(t *testing.T) {
+	engine := New()
+	engine.Use(Logging)
+	g := engine.Group("user")
+	g.Use(Recovery)
+	g.Get("/:id", routeInfoHandler)
+	g.Post("/", routeInfoHandler, HandlerMiddleware(routeInfoHandler))
+
+	routes := engine.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("routes = %v", routes)
+	}
+	get := routes[0]
+	if get.Method != http.MethodGet || get.Path != "/user/:id" || get.Handler != "github.com/JUYAFEI/go-framework.routeInfoHandler" {
+		t.Fatalf("route = %+v", get)
+	}
+	if len(get.Middlewares) != 2 || get.Middlewares[0] != "github.com/JUYAFEI/go-framework.Logging" || get.Middlewares[1] != "github.com/JUYAFEI/go-framework.Recovery" {
+		t.Fatalf("middlewares = %v", get.Middlewares)
+	}
+	if len(routes[1].Middlewares) != 3 {
+		t.Fatalf("middlewares = %v", routes[1].Middlewares)
+	}
+}
+
+func TestHostAndMatch(t *testing.T) {
+	engine := New()
+	engine.Group("").Get("/info", func(ctx *Context) {
+		ctx.W.Write([]byte("default"))
+	})
+	tenant := engine.Host("{tenant}.example.com")
+	tenant.Get("/info", func(ctx *Context) {
+		ctx.W.Write([]byte("tenant " + ctx.HostParam("tenant")))
+	})
+	api := engine.Group("api")
+	api.Get("/version", func(ctx *Context) {
+		ctx.W.Write([]byte("v1"))
+	})
+	api.Match(MatchHeader("X-API-Version", "2")).Get("/version", func(ctx *Context) {
+		ctx.W.Write([]byte("v2"))
+	})
+
+	tests := []struct {
+		host   string
+		path   string
+		header string
+		body   string
+	}{
+		{"acme.example.com:8080", "/info", "", "tenant acme"},
+		{"example.com", "/info", "", "default"},
+		{"a.b.example.com", "/info", "", "default"},
+		{"localhost", "/api/version", "", "v1"},
+		{"localhost", "/api/version", "2", "v2"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		req.Host = test.host
+		if test.header != "" {
+			req.Header.Set("X-API-Version", test.header)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Body.String() != test.body {
+			t.Fatalf("%s%s: body = %q, want %q", test.host, test.path, w.Body.String(), test.body)
+		}
+	}
+}
+
+func TestMount(t *testing.T) {
+	engine := New()
+	admin := New()
+	admin.Group("").Get("/users/:id", func(ctx *Context) {
+		ctx.W.Write([]byte("admin user " + ctx.Param("id")))
+	})
+
+	var order []string
+	g := engine.Group("api")
+	g.Use(orderMiddleware("group", &order))
+	g.Mount("/debug", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("debug " + req.URL.EscapedPath()))
+	}))
+	g.Mount("admin", admin)
+	g.Wrap("/raw", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.Header.Get("X-Std") + " " + req.URL.Path))
+	}), WrapMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			req.Header.Set("X-Std", "std")
+			next.ServeHTTP(w, req)
+		})
+	}))
+	g.Wrap("/status", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}), WrapMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			next.ServeHTTP(struct{ http.ResponseWriter }{w}, req)
+		})
+	}))
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodGet, "/api/debug", "debug /"},
+		{http.MethodPost, "/api/debug/vars/x", "debug /vars/x"},
+		{http.MethodGet, "/api/debug/a%2Fb", "debug /a%2Fb"},
+		{http.MethodGet, "/api/admin/users/3", "admin user 3"},
+		{http.MethodGet, "/api/raw", "std /api/raw"},
+	}
+	for _, test := range tests {
+		if w := performRequest(engine, test.method, test.path); w.Body.String() != test.body {
+			t.Fatalf("%s: body = %q, want %q", test.path, w.Body.String(), test.body)
+		}
+	}
+	if len(order) != len(tests) {
+		t.Fatalf("group middleware ran %d times", len(order))
+	}
+	if w := performRequest(engine, http.MethodGet, "/api/status"); w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d", w.Code)
+	}
+
+	// pprof 按完整路径匹配，使用 Wrap 注册
+	engine.Group("debug").Wrap("/pprof/*", http.DefaultServeMux)
+	if w := performRequest(engine, http.MethodGet, "/debug/pprof/"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "heap") {
+		t.Fatalf("index: code = %d", w.Code)
+	}
+	if w := performRequest(engine, http.MethodGet, "/debug/pprof/heap?debug=1"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "heap profile") {
+		t.Fatalf("heap: code = %d, body = %.100q", w.Code, w.Body.String())
+	}
+}
+
+func TestBindValidation(t *testing.T) {
+	engine := New()
+	g := engine.Group("api")
+	g.Post("/user", func(ctx *Context) {
+		var user struct {
+			Name  string `json:"name" validate:"required"`
+			Email string `json:"email" validate:"email"`
+		}
+		if err := ctx.BindJSON(&user); err != nil {
+			return
+		}
+		ctx.String(http.StatusOK, user.Name)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/user", strings.NewReader(`{"name":"tom","email":"tom@example.com"}`))
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "tom" {
+		t.Fatalf("code = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/user", strings.NewReader(`{"email":"tom"}`))
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	want := `{"errors":[{"field":"Name","tag":"required","message":"Name is required"},{"field":"Email","tag":"email","message":"Email must be a valid email address"}]}`
+	if w.Code != http.StatusBadRequest || strings.TrimSpace(w.Body.String()) != want {
+		t.Fatalf("code = %d, body = %s", w.Code, w.Body.String())
+	}
+}
+
+func TestSSEvent(t *testing.T) {
+	engine := New()
+	g := engine.Group("events")
+	g.Get("/orders", func(ctx *Context) {
+		id, _ := strconv.Atoi(ctx.LastEventID())
+		ctx.WriteEvent(render.SSEvent{Id: strconv.Itoa(id + 1), Event: "status", Retry: 3000, Data: "paid\nshipped"})
+		ctx.SSEvent("order", map[string]int{"id": id + 1})
+	})
+	req := httptest.NewRequest(http.MethodGet, "/events/orders", nil)
+	req.Header.Set("Last-Event-ID", "41")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Header().Get("Content-Type") != "text/event-stream" || !w.Flushed {
+		t.Fatalf("header = %v, flushed = %v", w.Header(), w.Flushed)
+	}
+	want := "id: 42\nevent: status\nretry: 3000\ndata: paid\ndata: shipped\n\nevent: order\ndata: {\"id\":42}\n\n"
+	if w.Body.String() != want {
+		t.Fatalf("body = %q", w.Body.String())
+	}
+}
+
+func TestStreamClientGone(t *testing.T) {
+	engine := New()
+	g := engine.Group("events")
+	steps := 0
+	var gone bool
+	g.Get("/ticks", func(ctx *Context) {
+		gone = ctx.Stream(func(w io.Writer) bool {
+			steps++
+			fmt.Fprintf(w, "data: %d\n\n", steps)
+			return true
+		})
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/events/ticks", nil).WithContext(ctx)
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+	if !gone || steps != 0 {
+		t.Fatalf("gone = %v, steps = %d", gone, steps)
+	}
+}
+
+type negotiateUser struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func TestNegotiate(t *testing.T) {
+	engine := New()
+	g := engine.Group("api")
+	var accepted []string
+	g.Get("/user", func(ctx *Context) {
+		accepted = ctx.Accepted()
+		ctx.Negotiate(http.StatusOK, Negotiate{
+			Offered:  []string{MIMEJSON, MIMEXML, MIMEHTML},
+			Data:     negotiateUser{Name: "tom"},
+			HTMLData: "<b>tom</b>",
+		})
+	})
+
+	tests := []struct {
+		accept string
+		code   int
+		body   string
+	}{
+		{"", http.StatusOK, `{"name":"tom"}`},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", http.StatusOK, "<b>tom</b>"},
+		{"application/*;q=0.5, application/xml", http.StatusOK, "<negotiateUser><name>tom</name></negotiateUser>"},
+		{"*/*;q=0.1, application/json;q=0", http.StatusOK, "<negotiateUser><name>tom</name></negotiateUser>"},
+		{"text/plain, image/*;q=0.5", http.StatusNotAcceptable, ""},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/user", nil)
+		if test.accept != "" {
+			req.Header.Set("Accept", test.accept)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != test.code || w.Body.String() != test.body {
+			t.Fatalf("accept %q: code = %d, body = %q", test.accept, w.Code, w.Body.String())
+		}
+	}
+	if strings.Join(accepted, ",") != "text/plain,image/*" {
+		t.Fatalf("accepted = %v", accepted)
+	}
+}
@@ -0,0 +1,312 @@
+package go_framework
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/JUYAFEI/go-framework/orm"
+	"github.com/JUYAFEI/go-framework/pool"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultAddr            = ":8111"
+	defaultShutdownTimeout = 10 * time.Second
+)
+
+// ServiceRegister 服务注册中心，register.MsRegister 实现了该接口
+type ServiceRegister interface {
+	DeregisterService(serviceName string, host string, port int) error
+	Close() error
+}
+
+// ServerConfig 服务配置
+type ServerConfig struct {
+	Network           string       // 监听的网络，tcp 或 unix，默认 tcp
+	Addr              string       // 监听地址，默认 :8111，unix 时为 socket 文件路径
+	UnixSocketMode    os.FileMode  // unix socket 文件的权限，为 0 时不修改
+	Listener          net.Listener // 设置后直接在该 Listener 上启动，忽略 Network 和 Addr
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	TLSConfig         *tls.Config
+	CertFile          string
+	KeyFile           string
+	H2C               bool          // 不使用 TLS 时支持 HTTP/2，包括 prior knowledge 和 Upgrade: h2c
+	HotRestart        bool          // 收到 SIGHUP 或 SIGUSR2 时启动新进程并把监听的 socket 交给它，新进程就绪后旧进程处理完请求退出
+	ShutdownTimeout   time.Duration // 优雅关闭时等待处理中请求的最长时间，默认 10 秒
+	Signals           []os.Signal   // 触发优雅关闭的信号，默认 SIGINT 和 SIGTERM
+	Pools             []*pool.Pool  // 关闭时释放的协程池
+	Dbs               []*orm.MsDb   // 关闭时关闭的数据库
+	Register          ServiceRegister
+	ServiceName       string // 从注册中心注销的服务
+	Host              string
+	Port              int
+}
+
+func (c *ServerConfig) isTLS() bool {
+	return c.CertFile != "" || c.TLSConfig != nil
+}
+
+// Server 根据配置创建 *http.Server，Handler 为 Engine
+func (e *Engine) Server(config ServerConfig) *http.Server {
+	if config.Addr == "" {
+		config.Addr = defaultAddr
+	}
+	srv := &http.Server{
+		Addr:              config.Addr,
+		Handler:           e,
+		TLSConfig:         config.TLSConfig,
+		ReadTimeout:       config.ReadTimeout,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+		MaxHeaderBytes:    config.MaxHeaderBytes,
+	}
+	if config.H2C {
+		h2s := &http2.Server{IdleTimeout: config.IdleTimeout}
+		// 注册 http2 的关闭回调，Shutdown 时通知 h2c 连接优雅关闭
+		if err := http2.ConfigureServer(srv, h2s); err != nil {
+			e.Logger.Error(err)
+		}
+		srv.Handler = h2c.NewHandler(e, h2s)
+	}
+	return srv
+}
+
+// Run 启动服务，默认监听 :8111，收到 SIGINT 或 SIGTERM 后优雅关闭
+func (e *Engine) Run(addr ...string) error {
+	config := ServerConfig{}
+	if len(addr) > 0 {
+		config.Addr = addr[0]
+	}
+	return e.RunWithConfig(config)
+}
+
+// RunTLS 以 https 启动服务
+func (e *Engine) RunTLS(addr string, certFile string, keyFile string) error {
+	return e.RunWithConfig(ServerConfig{Addr: addr, CertFile: certFile, KeyFile: keyFile})
+}
+
+// RunUnix 监听 unix socket，已存在的 socket 文件会先删除，关闭时删除 socket 文件
+func (e *Engine) RunUnix(file string) error {
+	return e.RunWithConfig(ServerConfig{Network: "unix", Addr: file})
+}
+
+// RunFd 在继承的文件描述符上启动服务，用于 systemd socket activation
+func (e *Engine) RunFd(fd int) error {
+	f := os.NewFile(uintptr(fd), fmt.Sprintf("fd@%d", fd))
+	ln, err := net.FileListener(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	return e.RunListener(ln)
+}
+
+// RunListener 在自定义的 net.Listener 上启动服务
+func (e *Engine) RunListener(ln net.Listener) error {
+	return e.RunWithConfig(ServerConfig{Listener: ln})
+}
+
+// RunWithConfig 按配置启动服务，收到关闭信号或调用 Shutdown 后停止接收新连接，
+// 等待处理中的请求结束，然后注销服务、释放协程池、关闭数据库
+func (e *Engine) RunWithConfig(config ServerConfig) error {
+	srv := e.Server(config)
+	ln := config.Listener
+	if ln == nil {
+		var err error
+		ln, err = inheritedListener()
+		if err != nil {
+			return err
+		}
+	}
+	if ln == nil {
+		var err error
+		ln, err = listen(config.Network, srv.Addr, config.UnixSocketMode)
+		if err != nil {
+			return err
+		}
+	}
+	return e.serve(srv, ln, config)
+}
+
+func listen(network string, addr string, mode os.FileMode) (net.Listener, error) {
+	if network == "" {
+		network = "tcp"
+	}
+	if network != "unix" {
+		return net.Listen(network, addr)
+	}
+	// 删除上次异常退出留下的 socket 文件，Listener 关闭时会自动删除
+	if info, err := os.Lstat(addr); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(addr); err != nil {
+			return nil, err
+		}
+	}
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if mode != 0 {
+		if err := os.Chmod(addr, mode); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+	return ln, nil
+}
+
+// runningServer 正在运行的服务，Shutdown 把关闭请求交给 serve 执行
+type runningServer struct {
+	shutdown chan shutdownRequest
+	stopped  chan struct{}
+}
+
+type shutdownRequest struct {
+	ctx  context.Context
+	done chan error
+}
+
+// Shutdown 优雅关闭正在运行的服务，等待处理中的请求结束并释放资源后返回，ctx 结束时不再等待
+func (e *Engine) Shutdown(ctx context.Context) error {
+	e.serverLock.Lock()
+	r := e.running
+	e.serverLock.Unlock()
+	if r == nil {
+		return errors.New("server is not running")
+	}
+	req := shutdownRequest{ctx: ctx, done: make(chan error, 1)}
+	select {
+	case r.shutdown <- req:
+	case <-r.stopped:
+		return errors.New("server is not running")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return <-req.done
+}
+
+func (e *Engine) serve(srv *http.Server, ln net.Listener, config ServerConfig) error {
+	r := &runningServer{shutdown: make(chan shutdownRequest), stopped: make(chan struct{})}
+	defer close(r.stopped)
+	e.serverLock.Lock()
+	e.running = r
+	e.serverLock.Unlock()
+	e.debugPrintRoutes()
+	e.Logger.Info(fmt.Sprintf("server listening on %s", ln.Addr()))
+
+	errChan := make(chan error, 1)
+	go func() {
+		if config.isTLS() {
+			errChan <- srv.ServeTLS(ln, config.CertFile, config.KeyFile)
+		} else {
+			errChan <- srv.Serve(ln)
+		}
+	}()
+	notifyReady()
+
+	signals := config.Signals
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+	}
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, signals...)
+	defer signal.Stop(quit)
+	restart := make(chan os.Signal, 1)
+	if config.HotRestart && len(restartSignals) > 0 {
+		signal.Notify(restart, restartSignals...)
+		defer signal.Stop(restart)
+	}
+
+	restarted := false
+	for !restarted {
+		select {
+		case err := <-errChan:
+			e.deregister(config, true)
+			e.releaseResources(config)
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return err
+		case sig := <-quit:
+			e.Logger.Info(fmt.Sprintf("received %s, shutting down server", sig))
+			e.deregister(config, true)
+			return e.shutdown(srv, config)
+		case req := <-r.shutdown:
+			e.Logger.Info("shutting down server")
+			e.deregister(config, true)
+			err := e.shutdownContext(req.ctx, srv, config)
+			req.done <- err
+			return err
+		case sig := <-restart:
+			e.Logger.Info(fmt.Sprintf("received %s, restarting server", sig))
+			if err := e.restart(ln); err != nil {
+				e.Logger.Error(fmt.Sprintf("restart failed: %v", err))
+				continue
+			}
+			restarted = true
+		}
+	}
+	// 新进程已经在同一个 socket 上接收连接，服务仍然在线，不需要从注册中心注销
+	e.deregister(config, false)
+	return e.shutdown(srv, config)
+}
+
+// shutdown 等待处理中的请求结束后释放资源
+func (e *Engine) shutdown(srv *http.Server, config ServerConfig) error {
+	timeout := config.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return e.shutdownContext(ctx, srv, config)
+}
+
+// shutdownContext 处理中的请求结束或者 ctx 结束后释放资源
+func (e *Engine) shutdownContext(ctx context.Context, srv *http.Server, config ServerConfig) error {
+	err := srv.Shutdown(ctx)
+	e.releaseResources(config)
+	return err
+}
+
+// deregister 先从注册中心注销，不再有新流量进入
+func (e *Engine) deregister(config ServerConfig, deregisterService bool) {
+	if config.Register == nil {
+		return
+	}
+	if deregisterService && config.ServiceName != "" {
+		if err := config.Register.DeregisterService(config.ServiceName, config.Host, config.Port); err != nil {
+			e.Logger.Error(err)
+		}
+	}
+	if err := config.Register.Close(); err != nil {
+		e.Logger.Error(err)
+	}
+}
+
+func (e *Engine) releaseResources(config ServerConfig) {
+	for _, p := range config.Pools {
+		p.Release()
+	}
+	for _, db := range config.Dbs {
+		if err := db.Close(); err != nil {
+			e.Logger.Error(err)
+		}
+	}
+	e.serverLock.Lock()
+	e.running = nil
+	e.serverLock.Unlock()
+	e.Logger.Info("server stopped")
+}
@@ -0,0 +1,340 @@
+package go_framework
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"github.com/JUYAFEI/go-framework/pool"
+	"github.com/JUYAFEI/go-framework/websocket"
+	"golang.org/x/net/http2"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type testRegister struct {
+	deregistered string
+	closed       bool
+}
+
+func (r *testRegister) DeregisterService(serviceName string, host string, port int) error {
+	r.deregistered = serviceName
+	return nil
+}
+
+func (r *testRegister) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	engine := New()
+	p, err := pool.NewPool(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Shutdown 时请求还在处理，协程池要等请求结束后才释放
+	started := make(chan struct{})
+	var poolOpen atomic.Bool
+	engine.Group("").Get("/slow", func(ctx *Context) {
+		close(started)
+		time.Sleep(300 * time.Millisecond)
+		poolOpen.Store(!p.IsClosed())
+		ctx.String(http.StatusOK, "done")
+	})
+	reg := &testRegister{}
+	done := make(chan error, 1)
+	go func() {
+		done <- engine.RunWithConfig(ServerConfig{
+			Listener:    ln,
+			Pools:       []*pool.Pool{p},
+			Register:    reg,
+			ServiceName: "order",
+		})
+	}()
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/slow")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("request not started")
+	}
+	if err := engine.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !poolOpen.Load() {
+		t.Fatal("pool released before the request finished")
+	}
+	if b := <-body; b != "done" {
+		t.Fatalf("body = %q", b)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if !p.IsClosed() || reg.deregistered != "order" || !reg.closed {
+		t.Fatalf("pool closed = %v, register = %+v", p.IsClosed(), reg)
+	}
+	if err := engine.Shutdown(context.Background()); err == nil {
+		t.Fatal("expected error after the server stopped")
+	}
+}
+
+func TestRunUnix(t *testing.T) {
+	engine := New()
+	engine.Group("").Get("/ping", func(ctx *Context) {
+		ctx.W.Write([]byte("pong"))
+	})
+	file := filepath.Join(t.TempDir(), "app.sock")
+	done := make(chan error, 1)
+	go func() {
+		done <- engine.RunWithConfig(ServerConfig{Network: "unix", Addr: file, UnixSocketMode: 0660})
+	}()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return net.Dial("unix", file)
+		},
+	}}
+	var resp *http.Response
+	var err error
+	deadline := time.Now().Add(time.Second)
+	for resp, err = client.Get("http://unix/ping"); err != nil; resp, err = client.Get("http://unix/ping") {
+		if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "pong" {
+		t.Fatalf("body = %q", body)
+	}
+	info, err := os.Stat(file)
+	if err != nil || info.Mode().Perm() != 0660 {
+		t.Fatalf("socket mode = %v, err = %v", info, err)
+	}
+
+	if err := engine.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("socket file not removed: %v", err)
+	}
+}
+
+func TestH2C(t *testing.T) {
+	engine := New()
+	engine.Group("").Get("/proto", func(ctx *Context) {
+		ctx.W.Write([]byte(ctx.Protocol()))
+	})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- engine.RunWithConfig(ServerConfig{Listener: ln, H2C: true})
+	}()
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+	resp, err := client.Get("http://" + ln.Addr().String() + "/proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "HTTP/2.0" {
+		t.Fatalf("body = %q", body)
+	}
+
+	if err := engine.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInheritedListener(t *testing.T) {
+	if ln, err := inheritedListener(); ln != nil || err != nil {
+		t.Fatalf("ln = %v, err = %v", ln, err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	f, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(listenerFdEnv, strconv.Itoa(int(f.Fd())))
+	inherited, err := inheritedListener()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inherited.Close()
+	if inherited.Addr().String() != ln.Addr().String() {
+		t.Fatalf("addr = %s, want %s", inherited.Addr(), ln.Addr())
+	}
+	if os.Getenv(listenerFdEnv) != "" {
+		t.Fatal("env not cleared")
+	}
+
+	// 继承的 unix socket 关闭时删除 socket 文件
+	file := filepath.Join(t.TempDir(), "app.sock")
+	uln, err := net.Listen("unix", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uln.(*net.UnixListener).SetUnlinkOnClose(false)
+	defer uln.Close()
+	f, err = uln.(*net.UnixListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(listenerFdEnv, strconv.Itoa(int(f.Fd())))
+	inherited, err = inheritedListener()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inherited.Close()
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("socket file not removed: %v", err)
+	}
+}
+
+// restartChildEnv TestRestart 启动的新进程通过该环境变量决定行为
+const restartChildEnv = "GO_FRAMEWORK_TEST_RESTART"
+
+func TestRestart(t *testing.T) {
+	switch os.Getenv(restartChildEnv) {
+	case "serve":
+		engine := New()
+		engine.Group("").Get("/pid", func(ctx *Context) {
+			ctx.String(http.StatusOK, strconv.Itoa(os.Getpid()))
+		})
+		engine.RunWithConfig(ServerConfig{})
+		os.Exit(0)
+	case "exit":
+		os.Exit(1)
+	}
+	if len(restartSignals) == 0 {
+		t.Skip("hot restart is not supported")
+	}
+
+	// 新进程只运行当前测试
+	args := os.Args
+	os.Args = []string{args[0], "-test.run=^TestRestart$"}
+	defer func() {
+		os.Args = args
+	}()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	engine := New()
+
+	t.Setenv(restartChildEnv, "exit")
+	if err := engine.restart(ln); err == nil {
+		t.Fatal("expected error when the new process exits before ready")
+	}
+
+	t.Setenv(restartChildEnv, "serve")
+	if err := engine.restart(ln); err != nil {
+		t.Fatal(err)
+	}
+	// 当前进程没有在 ln 上接收连接，请求只能由新进程处理
+	resp, err := http.Get("http://" + ln.Addr().String() + "/pid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	pid, err := strconv.Atoi(string(body))
+	if err != nil || pid == os.Getpid() {
+		t.Fatalf("body = %q", body)
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	// 新进程退出后被回收，不会留下僵尸进程
+	deadline := time.Now().Add(5 * time.Second)
+	for p.Signal(syscall.Signal(0)) == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("new process not reaped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWebSocketRoute(t *testing.T) {
+	engine := New()
+	g := engine.Group("ws")
+	g.WebSocket("/echo", func(ctx *Context) {
+		ctx.WS.WriteMessage(websocket.TextMessage, []byte("hi "+ctx.GetQuery("name")))
+	})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: engine}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, "GET /ws/echo?name=tom HTTP/1.1\r\nHost: "+ln.Addr().String()+
+		"\r\nConnection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil || resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("resp = %v, err = %v", resp, err)
+	}
+	frame := make([]byte, 8)
+	if _, err := io.ReadFull(br, frame); err != nil || string(frame) != "\x81\x06hi tom" {
+		t.Fatalf("frame = %q, err = %v", frame, err)
+	}
+
+	resp, err = http.Get("http://" + ln.Addr().String() + "/ws/echo")
+	if err != nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("resp = %v, err = %v", resp, err)
+	}
+}
@@ -1,83 +1,357 @@
 package go_framework
 
 import (
+	"fmt"
 	"strings"
 )
 
+// Param 路由参数，:id 和 * 匹配到的值
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params 按匹配顺序保存的路由参数
+type Params []Param
+
+// Get 返回第一个 key 匹配的参数值
+func (ps Params) Get(name string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// ByName 返回参数值，不存在时返回空字符串
+func (ps Params) ByName(name string) (value string) {
+	value, _ = ps.Get(name)
+	return
+}
+
+type nodeKind uint8
+
+const (
+	staticKind nodeKind = iota
+	paramKind
+	wildKind
+)
+
+// Tree 压缩前缀树（radix tree）路由
+// 静态节点按公共前缀压缩，参数节点 :name 匹配一段路径，通配节点 *name 匹配剩余全部路径，
+// 查找时优先级为 静态 > 参数 > 通配，匹配失败时回溯尝试下一种节点。
+// 参数可以带约束，如 :id<int>、:name<[a-z]+\.txt>，不满足约束的值不会匹配该节点
 type Tree struct {
-	Name       string
-	Children   []*Tree
-	RouterName string
+	Name       string  // 节点保存的路径片段
+	Children   []*Tree // 静态子节点
+	RouterName string  // 完整的路由，只在 IsEnd 节点上设置
 	IsEnd      bool
+	kind       nodeKind
+	key        string // 参数名
+	matcher    ParamMatcher
+	indices    string  // 静态子节点的首字节，与 Children 一一对应
+	params     []*Tree // 参数子节点，有约束的在前，没有约束的最多一个且在最后
+	wildChild  *Tree
 }
 
 // put path： /user/get/:id
 
+// Put 注册路由，同一位置的参数名或通配名不一致时 panic
 func (t *Tree) Put(path string) {
-	root := t
-	strs := strings.Split(path, "/")
-	for index, name := range strs {
-		if index == 0 {
-			continue
+	checkPath(path)
+	n := t.insert(path)
+	n.IsEnd = true
+	n.RouterName = path
+}
+
+//get path: /user/get/1
+
+func (t *Tree) Get(path string) *Tree {
+	return t.Search(path, nil)
+}
+
+// Search 查找路由，并将 :name 和 * 匹配到的值追加到 params 中，params 为 nil 时不收集
+func (t *Tree) Search(path string, params *Params) *Tree {
+	if !strings.HasPrefix(path, t.Name) {
+		return nil
+	}
+	if params == nil {
+		params = &Params{}
+	}
+	return t.search(path[len(t.Name):], params)
+}
+
+func (t *Tree) search(path string, params *Params) *Tree {
+	if path == "" {
+		if t.IsEnd {
+			return t
 		}
-		children := t.Children
-		isMatch := false
-		for _, node := range children {
-			if node.Name == name {
-				isMatch = true
-				t = node
-				break
-			}
+		if t.wildChild != nil {
+			*params = append(*params, Param{Key: t.wildChild.key, Value: ""})
+			return t.wildChild
 		}
-		if !isMatch {
-			isEnd := false
-			if index == len(strs)-1 {
-				isEnd = true
+		return nil
+	}
+	if i := strings.IndexByte(t.indices, path[0]); i >= 0 {
+		child := t.Children[i]
+		if strings.HasPrefix(path, child.Name) {
+			if node := child.search(path[len(child.Name):], params); node != nil {
+				return node
 			}
-			node := &Tree{
-				Name:     name,
-				Children: make([]*Tree, 0),
-				IsEnd:    isEnd,
+		}
+	}
+	if len(t.params) > 0 {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end > 0 {
+			value := path[:end]
+			for _, child := range t.params {
+				if child.matcher != nil && !child.matcher(value) {
+					continue
+				}
+				mark := len(*params)
+				*params = append(*params, Param{Key: child.key, Value: value})
+				if node := child.search(path[end:], params); node != nil {
+					return node
+				}
+				*params = (*params)[:mark]
 			}
-			children = append(children, node)
-			t.Children = children
-			t = node
 		}
 	}
-	t = root
+	if t.wildChild != nil {
+		*params = append(*params, Param{Key: t.wildChild.key, Value: path})
+		return t.wildChild
+	}
+	return nil
 }
 
-//get path: /user/get/1
+// FindCaseInsensitive 忽略大小写查找路由，返回按注册时大小写修正后的路径，
+// fixTrailingSlash 为 true 时同时修正末尾多余或缺少的 /
+func (t *Tree) FindCaseInsensitive(path string, fixTrailingSlash bool) (string, bool) {
+	if len(path) < len(t.Name) || !strings.EqualFold(path[:len(t.Name)], t.Name) {
+		if fixTrailingSlash && t.IsEnd && strings.EqualFold(path+"/", t.Name) {
+			return t.Name, true
+		}
+		return "", false
+	}
+	rest, ok := t.findCaseInsensitive(path[len(t.Name):], fixTrailingSlash)
+	if !ok {
+		return "", false
+	}
+	return t.Name + rest, true
+}
 
-func (t *Tree) Get(path string) *Tree {
-	strs := strings.Split(path, "/")
-	routerName := ""
-	for index, name := range strs {
-		if index == 0 {
-			continue
+func (t *Tree) findCaseInsensitive(path string, fixTrailingSlash bool) (string, bool) {
+	if path == "" {
+		if t.IsEnd || t.wildChild != nil {
+			return "", true
 		}
-		children := t.Children
-		isMatch := false
-		for _, node := range children {
-			if node.Name == name || node.Name == "*" || strings.Contains(node.Name, ":") {
-				isMatch = true
-				routerName += "/" + node.Name
-				node.RouterName = routerName
-				t = node
-				if index == len(strs)-1 {
-					return node
+		if fixTrailingSlash {
+			if i := strings.IndexByte(t.indices, '/'); i >= 0 {
+				child := t.Children[i]
+				if child.Name == "/" && (child.IsEnd || child.wildChild != nil) {
+					return "/", true
 				}
-				break
 			}
 		}
-		if !isMatch {
-			for _, node := range children {
-				if node.Name == "*" {
-					return node
+		return "", false
+	}
+	if fixTrailingSlash && path == "/" && t.IsEnd {
+		return "", true
+	}
+	for _, child := range t.Children {
+		if rest, ok := child.FindCaseInsensitive(path, fixTrailingSlash); ok {
+			return rest, true
+		}
+	}
+	if len(t.params) > 0 {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end > 0 {
+			value := path[:end]
+			for _, child := range t.params {
+				if child.matcher != nil && !child.matcher(value) {
+					continue
+				}
+				if rest, ok := child.findCaseInsensitive(path[end:], fixTrailingSlash); ok {
+					return value + rest, true
 				}
 			}
 		}
+	}
+	if t.wildChild != nil {
+		return path, true
+	}
+	return "", false
+}
 
+func (t *Tree) insert(path string) *Tree {
+	n := t
+	common := commonPrefix(path, n.Name)
+	if common < len(n.Name) {
+		n.split(common)
 	}
-	return nil
+	path = path[common:]
+	for path != "" {
+		switch path[0] {
+		case ':':
+			end := paramEnd(path)
+			n = n.addParam(path[:end])
+			path = path[end:]
+		case '*':
+			if n.wildChild == nil {
+				n.wildChild = &Tree{Name: path, kind: wildKind, key: wildKey(path)}
+			} else if n.wildChild.Name != path {
+				panic(fmt.Sprintf("%s conflicts with existing wildcard %s", path, n.wildChild.Name))
+			}
+			return n.wildChild
+		default:
+			i := strings.IndexByte(n.indices, path[0])
+			if i < 0 {
+				end := strings.IndexAny(path, ":*")
+				if end < 0 {
+					end = len(path)
+				}
+				child := &Tree{Name: path[:end], Children: make([]*Tree, 0)}
+				n.indices += string(path[0])
+				n.Children = append(n.Children, child)
+				n = child
+				path = path[end:]
+				continue
+			}
+			child := n.Children[i]
+			common := commonPrefix(path, child.Name)
+			if common < len(child.Name) {
+				child.split(common)
+			}
+			n = child
+			path = path[common:]
+		}
+	}
+	return n
+}
+
+// split 把节点拆分为公共前缀和剩余部分，剩余部分继承原节点的子节点
+func (t *Tree) split(i int) {
+	child := &Tree{
+		Name:       t.Name[i:],
+		Children:   t.Children,
+		RouterName: t.RouterName,
+		IsEnd:      t.IsEnd,
+		indices:    t.indices,
+		params:     t.params,
+		wildChild:  t.wildChild,
+	}
+	t.Name = t.Name[:i]
+	t.Children = []*Tree{child}
+	t.RouterName = ""
+	t.IsEnd = false
+	t.indices = child.Name[:1]
+	t.params = nil
+	t.wildChild = nil
+}
+
+// addParam 添加参数子节点，同一位置没有约束的参数只能有一个
+func (t *Tree) addParam(segment string) *Tree {
+	for _, child := range t.params {
+		if child.Name == segment {
+			return child
+		}
+	}
+	key, constraint := splitParam(segment)
+	child := &Tree{Name: segment, kind: paramKind, key: key}
+	if constraint == "" {
+		if last := len(t.params) - 1; last >= 0 && t.params[last].matcher == nil {
+			panic(fmt.Sprintf("%s conflicts with existing wildcard %s", segment, t.params[last].Name))
+		}
+		t.params = append(t.params, child)
+		return child
+	}
+	child.matcher = compileConstraint(constraint)
+	i := len(t.params)
+	if i > 0 && t.params[i-1].matcher == nil {
+		i--
+	}
+	t.params = append(t.params, nil)
+	copy(t.params[i+1:], t.params[i:])
+	t.params[i] = child
+	return child
+}
+
+// paramEnd 参数片段的结束位置，约束以 > 结尾，约束中可以包含 /
+func paramEnd(path string) int {
+	end := strings.IndexByte(path, '/')
+	if end < 0 {
+		end = len(path)
+	}
+	open := strings.IndexByte(path[:end], '<')
+	if open < 0 {
+		return end
+	}
+	for i := open + 1; i < len(path); i++ {
+		if path[i] == '>' && (i == len(path)-1 || path[i+1] == '/') {
+			return i + 1
+		}
+	}
+	return len(path)
+}
+
+// splitParam :id<int> 拆分为 id 和 int
+func splitParam(segment string) (string, string) {
+	name := segment[1:]
+	open := strings.IndexByte(name, '<')
+	if open < 0 {
+		return name, ""
+	}
+	return name[:open], name[open+1 : len(name)-1]
+}
+
+// checkPath 参数和通配必须是完整的一段路径，通配只能出现在最后
+func checkPath(path string) {
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if c != ':' && c != '*' {
+			continue
+		}
+		if i == 0 || path[i-1] != '/' {
+			panic(fmt.Sprintf("wildcard must start a path segment in %s", path))
+		}
+		if c == '*' {
+			if strings.IndexByte(path[i:], '/') >= 0 {
+				panic(fmt.Sprintf("catch-all is only allowed at the end of the path in %s", path))
+			}
+			return
+		}
+		end := i + paramEnd(path[i:])
+		segment := path[i:end]
+		key, constraint := splitParam(segment)
+		if key == "" || strings.ContainsAny(key, ":*>") {
+			panic(fmt.Sprintf("param must be named in %s", path))
+		}
+		if strings.IndexByte(segment, '<') >= 0 && (constraint == "" || segment[len(segment)-1] != '>') {
+			panic(fmt.Sprintf("invalid param constraint %s in %s", segment, path))
+		}
+		i = end - 1
+	}
+}
+
+// wildKey 通配节点 *name 的参数名，只写 * 时为 *
+func wildKey(name string) string {
+	if name == "*" {
+		return "*"
+	}
+	return name[1:]
+}
+
+func commonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
 }
@@ -2,6 +2,7 @@ package go_framework
 
 import (
 	"fmt"
+	"strconv"
 	"testing"
 )
 
@@ -21,3 +22,142 @@ func TestTree(t *testing.T) {
 	node = tree.Get("/user/create/hello")
 	fmt.Println(node)
 }
+
+func TestTreeParams(t *testing.T) {
+	tree := &Tree{
+		Name:     "/",
+		Children: make([]*Tree, 0),
+	}
+	tree.Put("/user/get/:id")
+	tree.Put("/static/*")
+
+	var params Params
+	node := tree.Search("/user/get/1", &params)
+	if node == nil || node.RouterName != "/user/get/:id" {
+		t.Fatalf("unexpected node %v", node)
+	}
+	if v := params.ByName("id"); v != "1" {
+		t.Fatalf("id = %q, want 1", v)
+	}
+
+	params = params[:0]
+	if node = tree.Search("/static/app.js", &params); node == nil {
+		t.Fatal("wildcard not matched")
+	}
+	if v := params.ByName("*"); v != "app.js" {
+		t.Fatalf("* = %q, want app.js", v)
+	}
+}
+
+func TestTreePriority(t *testing.T) {
+	tree := &Tree{
+		Name:     "/",
+		Children: make([]*Tree, 0),
+	}
+	tree.Put("/user/:id")
+	tree.Put("/user/profile")
+	tree.Put("/user/:id/edit")
+	tree.Put("/user/profile/view")
+	tree.Put("/user/*path")
+	tree.Put("/users")
+
+	tests := []struct {
+		path   string
+		router string
+		params Params
+	}{
+		{"/user/profile", "/user/profile", nil},
+		{"/user/1", "/user/:id", Params{{"id", "1"}}},
+		{"/user/profile/view", "/user/profile/view", nil},
+		{"/user/profile/edit", "/user/:id/edit", Params{{"id", "profile"}}},
+		{"/user/1/2/3", "/user/*path", Params{{"path", "1/2/3"}}},
+		{"/users", "/users", nil},
+	}
+	for _, test := range tests {
+		var params Params
+		node := tree.Search(test.path, &params)
+		if node == nil || node.RouterName != test.router {
+			t.Fatalf("%s: got %v, want %s", test.path, node, test.router)
+		}
+		if len(params) != len(test.params) {
+			t.Fatalf("%s: params %v, want %v", test.path, params, test.params)
+		}
+		for i := range params {
+			if params[i] != test.params[i] {
+				t.Fatalf("%s: params %v, want %v", test.path, params, test.params)
+			}
+		}
+	}
+	if node := tree.Get("/use"); node != nil {
+		t.Fatalf("/use matched %s", node.RouterName)
+	}
+}
+
+func TestTreeConflict(t *testing.T) {
+	tree := &Tree{
+		Name:     "/",
+		Children: make([]*Tree, 0),
+	}
+	tree.Put("/user/:id")
+	for _, path := range []string{"/user/:name", "/file/*a/b", "/user:id"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%s: expected panic", path)
+				}
+			}()
+			tree.Put(path)
+		}()
+	}
+}
+
+func TestTreeConstraint(t *testing.T) {
+	tree := &Tree{
+		Name:     "/",
+		Children: make([]*Tree, 0),
+	}
+	tree.Put("/user/:name")
+	tree.Put("/user/:id<int>")
+	tree.Put(`/file/:name<[a-z]+\.txt>`)
+	tree.Put("/date/:d<date>/list")
+	tree.Put("/path/:p<[^/]+>")
+
+	tests := []struct {
+		path   string
+		router string
+		value  string
+	}{
+		{"/user/12", "/user/:id<int>", "12"},
+		{"/user/tom", "/user/:name", "tom"},
+		{"/file/a.txt", `/file/:name<[a-z]+\.txt>`, "a.txt"},
+		{"/file/A.txt", "", ""},
+		{"/date/2024-02-29/list", "/date/:d<date>/list", "2024-02-29"},
+		{"/date/2023-02-29/list", "", ""},
+		{"/path/a", "/path/:p<[^/]+>", "a"},
+	}
+	for _, test := range tests {
+		var params Params
+		node := tree.Search(test.path, &params)
+		if test.router == "" {
+			if node != nil {
+				t.Fatalf("%s: matched %s", test.path, node.RouterName)
+			}
+			continue
+		}
+		if node == nil || node.RouterName != test.router {
+			t.Fatalf("%s: got %v, want %s", test.path, node, test.router)
+		}
+		if len(params) != 1 || params[0].Value != test.value {
+			t.Fatalf("%s: params %v", test.path, params)
+		}
+	}
+
+	RegisterParamType("even", func(value string) bool {
+		n, err := strconv.Atoi(value)
+		return err == nil && n%2 == 0
+	})
+	tree.Put("/num/:n<even>")
+	if tree.Get("/num/4") == nil || tree.Get("/num/3") != nil {
+		t.Fatal("custom param type not applied")
+	}
+}
@@ -0,0 +1,73 @@
+package go_framework
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// URLFor 生成命名路由的 URL，params 为 key, value 交替的参数，
+// 用于填充 :name 和 *name，多余的参数作为查询字符串，缺少路由参数时返回错误
+func (e *Engine) URLFor(name string, params ...any) (string, error) {
+	route, ok := e.namedRoutes[name]
+	if !ok {
+		return "", fmt.Errorf("route %s not found", name)
+	}
+	if len(params)%2 != 0 {
+		return "", errors.New("params must be key value pairs")
+	}
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		key, ok := params[i].(string)
+		if !ok {
+			return "", fmt.Errorf("param key %v must be string", params[i])
+		}
+		values[key] = fmt.Sprint(params[i+1])
+	}
+
+	var sb strings.Builder
+	path := route.Path
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if (c != ':' && c != '*') || i == 0 || path[i-1] != '/' {
+			sb.WriteByte(c)
+			continue
+		}
+		if c == '*' {
+			key := wildKey(path[i:])
+			value, ok := values[key]
+			if !ok {
+				return "", fmt.Errorf("missing param %s for route %s", key, name)
+			}
+			delete(values, key)
+			segments := strings.Split(value, "/")
+			for j, segment := range segments {
+				segments[j] = url.PathEscape(segment)
+			}
+			sb.WriteString(strings.Join(segments, "/"))
+			break
+		}
+		end := i + paramEnd(path[i:])
+		key, constraint := splitParam(path[i:end])
+		value, ok := values[key]
+		if !ok {
+			return "", fmt.Errorf("missing param %s for route %s", key, name)
+		}
+		if constraint != "" && !compileConstraint(constraint)(value) {
+			return "", fmt.Errorf("param %s=%s does not match <%s> for route %s", key, value, constraint, name)
+		}
+		delete(values, key)
+		sb.WriteString(url.PathEscape(value))
+		i = end - 1
+	}
+
+	if len(values) > 0 {
+		query := make(url.Values, len(values))
+		for key, value := range values {
+			query.Set(key, value)
+		}
+		sb.WriteString("?" + query.Encode())
+	}
+	return sb.String(), nil
+}
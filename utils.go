@@ -1,6 +1,9 @@
 package go_framework
 
 import (
+	"path"
+	"reflect"
+	"runtime"
 	"strings"
 	"unicode"
 	"unsafe"
@@ -24,6 +27,35 @@ func SubStringLast(str string, substr string) string {
 	return str[index+len:]
 }
 
+// joinPrefix 拼接分组前缀，结果以 / 开头且不以 / 结尾，根分组为空字符串
+func joinPrefix(prefix string, name string) string {
+	name = strings.Trim(name, "/")
+	if name == "" {
+		return prefix
+	}
+	return prefix + "/" + name
+}
+
+// cleanPath 去掉路径中多余的 /、. 和 ..，保留末尾的 /
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	cleaned := path.Clean(p)
+	if p[len(p)-1] == '/' && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// nameOfFunction 返回函数名，用于打印路由和中间件
+func nameOfFunction(f any) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
+
 func StringToBytes(s string) []byte {
 	return *(*[]byte)(unsafe.Pointer(
 		&struct {
@@ -0,0 +1,62 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// FieldError 单个字段的校验错误
+type FieldError struct {
+	// Field 字段路径，如 Items[0].Name
+	Field string
+	Tag   string
+	Param string
+	Value any
+}
+
+func (e *FieldError) Error() string {
+	switch e.Tag {
+	case "required":
+		return fmt.Sprintf("%s is required", e.Field)
+	case "min":
+		return fmt.Sprintf("%s must be at least %s", e.Field, e.Param)
+	case "max":
+		return fmt.Sprintf("%s must be at most %s", e.Field, e.Param)
+	case "len":
+		return fmt.Sprintf("%s must have length %s", e.Field, e.Param)
+	case "email":
+		return fmt.Sprintf("%s must be a valid email address", e.Field)
+	case "oneof":
+		return fmt.Sprintf("%s must be one of [%s]", e.Field, e.Param)
+	case "regexp":
+		return fmt.Sprintf("%s must match %s", e.Field, e.Param)
+	case "eqfield":
+		return fmt.Sprintf("%s must be equal to %s", e.Field, e.Param)
+	}
+	if e.Param != "" {
+		return fmt.Sprintf("%s failed on the %s=%s rule", e.Field, e.Tag, e.Param)
+	}
+	return fmt.Sprintf("%s failed on the %s rule", e.Field, e.Tag)
+}
+
+// MarshalJSON 输出时附带 message
+func (e *FieldError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Field   string `json:"field"`
+		Tag     string `json:"tag"`
+		Param   string `json:"param,omitempty"`
+		Message string `json:"message"`
+	}{e.Field, e.Tag, e.Param, e.Error()})
+}
+
+// ValidationErrors 一次校验中所有失败的字段
+type ValidationErrors []*FieldError
+
+func (errs ValidationErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
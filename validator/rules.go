@@ -0,0 +1,99 @@
+package validator
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var builtins = map[string]Func{
+	"min":     isMin,
+	"max":     isMax,
+	"len":     hasLen,
+	"email":   isEmail,
+	"oneof":   isOneOf,
+	"eqfield": isEqField,
+}
+
+// size 数字返回数值，字符串返回字符数，切片和 map 返回长度
+func size(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Chan:
+		return float64(v.Len()), true
+	}
+	return 0, false
+}
+
+func compareParam(fl FieldLevel, cmp func(n, param float64) bool) bool {
+	n, ok := size(fl.Field)
+	if !ok {
+		return false
+	}
+	param, err := strconv.ParseFloat(fl.Param, 64)
+	if err != nil {
+		return false
+	}
+	return cmp(n, param)
+}
+
+func isMin(fl FieldLevel) bool {
+	return compareParam(fl, func(n, param float64) bool { return n >= param })
+}
+
+func isMax(fl FieldLevel) bool {
+	return compareParam(fl, func(n, param float64) bool { return n <= param })
+}
+
+func hasLen(fl FieldLevel) bool {
+	return compareParam(fl, func(n, param float64) bool { return n == param })
+}
+
+func isEmail(fl FieldLevel) bool {
+	if fl.Field.Kind() != reflect.String {
+		return false
+	}
+	s := fl.Field.String()
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s && addr.Name == ""
+}
+
+// isOneOf 参数用空格分隔，如 oneof=red green blue
+func isOneOf(fl FieldLevel) bool {
+	var s string
+	switch fl.Field.Kind() {
+	case reflect.String:
+		s = fl.Field.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(fl.Field.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s = strconv.FormatUint(fl.Field.Uint(), 10)
+	default:
+		s = fmt.Sprint(interfaceOf(fl.Field))
+	}
+	for _, option := range strings.Fields(fl.Param) {
+		if option == s {
+			return true
+		}
+	}
+	return false
+}
+
+// isEqField 与同一结构体中 Param 指定的字段相等
+func isEqField(fl FieldLevel) bool {
+	other := indirect(fl.Parent.FieldByName(fl.Param))
+	if !other.IsValid() || other.Type() != fl.Field.Type() || !other.Comparable() {
+		return false
+	}
+	return fl.Field.Equal(other)
+}
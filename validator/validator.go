@@ -0,0 +1,329 @@
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FieldLevel 传给校验函数的字段信息
+type FieldLevel struct {
+	// Parent 字段所在的结构体
+	Parent reflect.Value
+	// Field 字段的值，指针已经解引用
+	Field reflect.Value
+	Param string
+}
+
+// Func 校验函数，返回 false 表示校验失败
+type Func func(fl FieldLevel) bool
+
+// Validate 按 validate tag 校验结构体
+//
+//	type User struct {
+//		Name     string   `validate:"required,min=2,max=20"`
+//		Email    string   `validate:"omitempty,email"`
+//		Role     string   `validate:"oneof=admin user"`
+//		Password string   `validate:"required"`
+//		Confirm  string   `validate:"eqfield=Password"`
+//		Tags     []string `validate:"max=5,dive,required"`
+//	}
+//
+// 多个规则用逗号分隔，regexp 规则会占用 tag 剩余的全部内容，
+// dive 之前的规则校验切片或 map 本身，之后的规则校验其中的每个元素，
+// 嵌套的结构体会自动校验
+type Validate struct {
+	mu      sync.RWMutex
+	funcs   map[string]Func
+	rules   sync.Map
+	regexps sync.Map
+}
+
+var defaultValidate = New()
+
+func New() *Validate {
+	v := &Validate{funcs: make(map[string]Func)}
+	for tag, fn := range builtins {
+		v.funcs[tag] = fn
+	}
+	v.funcs["regexp"] = v.matchRegexp
+	return v
+}
+
+// Default 返回包级别使用的 Validate
+func Default() *Validate {
+	return defaultValidate
+}
+
+// RegisterValidation 注册自定义规则，同名规则会被覆盖
+func RegisterValidation(tag string, fn Func) {
+	defaultValidate.RegisterValidation(tag, fn)
+}
+
+// Struct 使用默认的 Validate 校验结构体
+func Struct(obj any) error {
+	return defaultValidate.Struct(obj)
+}
+
+func (v *Validate) RegisterValidation(tag string, fn Func) {
+	if tag == "" || tag == "dive" || tag == "omitempty" || tag == "required" || strings.ContainsAny(tag, ",=") {
+		panic(fmt.Sprintf("validator: invalid tag name %q", tag))
+	}
+	if fn == nil {
+		panic("validator: nil validation func for " + tag)
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.funcs[tag] = fn
+}
+
+// Struct 校验结构体或结构体指针，校验失败返回 ValidationErrors，其他类型不做校验
+func (v *Validate) Struct(obj any) error {
+	value := reflect.ValueOf(obj)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+	var errs ValidationErrors
+	v.validateStruct(&errs, "", value)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+type rule struct {
+	tag   string
+	param string
+}
+
+type fieldRules struct {
+	omitempty bool
+	required  bool
+	rules     []rule
+	dive      *fieldRules
+}
+
+type structField struct {
+	index     int
+	name      string
+	anonymous bool
+	rules     *fieldRules
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (v *Validate) validateStruct(errs *ValidationErrors, prefix string, value reflect.Value) {
+	for _, field := range v.structFields(value.Type()) {
+		fv := value.Field(field.index)
+		if field.anonymous {
+			v.validateNested(errs, prefix, fv)
+			continue
+		}
+		v.validateValue(errs, prefix+field.name, value, fv, field.rules)
+	}
+}
+
+func (v *Validate) validateValue(errs *ValidationErrors, path string, parent reflect.Value, fv reflect.Value, rules *fieldRules) {
+	if rules != nil {
+		if !hasValue(fv) {
+			if rules.required {
+				*errs = append(*errs, &FieldError{Field: path, Tag: "required", Value: interfaceOf(fv)})
+			}
+			if rules.required || rules.omitempty || isNil(fv) {
+				return
+			}
+		}
+		fv = indirect(fv)
+		for _, r := range rules.rules {
+			if !v.call(r, FieldLevel{Parent: parent, Field: fv, Param: r.param}) {
+				*errs = append(*errs, &FieldError{Field: path, Tag: r.tag, Param: r.param, Value: interfaceOf(fv)})
+				return
+			}
+		}
+		if rules.dive != nil {
+			v.dive(errs, path, parent, fv, rules.dive)
+			return
+		}
+	}
+	v.validateNested(errs, path+".", fv)
+}
+
+func (v *Validate) validateNested(errs *ValidationErrors, prefix string, fv reflect.Value) {
+	fv = indirect(fv)
+	if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+		v.validateStruct(errs, prefix, fv)
+	}
+}
+
+func (v *Validate) dive(errs *ValidationErrors, path string, parent reflect.Value, fv reflect.Value, rules *fieldRules) {
+	switch fv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < fv.Len(); i++ {
+			v.validateValue(errs, fmt.Sprintf("%s[%d]", path, i), parent, fv.Index(i), rules)
+		}
+	case reflect.Map:
+		iter := fv.MapRange()
+		for iter.Next() {
+			v.validateValue(errs, fmt.Sprintf("%s[%v]", path, iter.Key().Interface()), parent, iter.Value(), rules)
+		}
+	}
+}
+
+func (v *Validate) call(r rule, fl FieldLevel) bool {
+	v.mu.RLock()
+	fn, ok := v.funcs[r.tag]
+	v.mu.RUnlock()
+	if !ok {
+		panic("validator: undefined validation " + r.tag)
+	}
+	return fn(fl)
+}
+
+// structFields 解析并缓存结构体的校验规则
+func (v *Validate) structFields(t reflect.Type) []structField {
+	if cached, ok := v.rules.Load(t); ok {
+		return cached.([]structField)
+	}
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("validate")
+		if tag == "-" {
+			continue
+		}
+		if sf.Anonymous && tag == "" {
+			if ft := derefType(sf.Type); ft.Kind() == reflect.Struct {
+				fields = append(fields, structField{index: i, anonymous: true})
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		field := structField{index: i, name: sf.Name}
+		if tag != "" {
+			field.rules = v.parseRules(t, sf, tag)
+		} else if ft := derefType(sf.Type); ft.Kind() != reflect.Struct || ft == timeType {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	cached, _ := v.rules.LoadOrStore(t, fields)
+	return cached.([]structField)
+}
+
+func (v *Validate) parseRules(parent reflect.Type, sf reflect.StructField, tag string) *fieldRules {
+	head := &fieldRules{}
+	current := head
+	for tag != "" {
+		var item string
+		if strings.HasPrefix(tag, "regexp=") {
+			item, tag = tag, ""
+		} else {
+			item, tag, _ = strings.Cut(tag, ",")
+		}
+		name, param, _ := strings.Cut(strings.TrimSpace(item), "=")
+		switch name {
+		case "":
+		case "omitempty":
+			current.omitempty = true
+		case "required":
+			current.required = true
+		case "dive":
+			current.dive = &fieldRules{}
+			current = current.dive
+		default:
+			v.checkRule(parent, sf, name, param)
+			current.rules = append(current.rules, rule{tag: name, param: param})
+		}
+	}
+	return head
+}
+
+// checkRule 在解析 tag 时检查参数，配置错误直接 panic
+func (v *Validate) checkRule(parent reflect.Type, sf reflect.StructField, name string, param string) {
+	v.mu.RLock()
+	_, ok := v.funcs[name]
+	v.mu.RUnlock()
+	if !ok {
+		panic(fmt.Sprintf("validator: undefined validation %q on %s.%s", name, parent.Name(), sf.Name))
+	}
+	switch name {
+	case "min", "max", "len":
+		if _, err := strconv.ParseFloat(param, 64); err != nil {
+			panic(fmt.Sprintf("validator: invalid %s param %q on %s.%s", name, param, parent.Name(), sf.Name))
+		}
+	case "regexp":
+		v.compile(param)
+	case "eqfield":
+		if _, ok := parent.FieldByName(param); !ok {
+			panic(fmt.Sprintf("validator: eqfield %q not found on %s", param, parent.Name()))
+		}
+	}
+}
+
+func (v *Validate) compile(pattern string) *regexp.Regexp {
+	if re, ok := v.regexps.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, _ := v.regexps.LoadOrStore(pattern, regexp.MustCompile(pattern))
+	return re.(*regexp.Regexp)
+}
+
+func (v *Validate) matchRegexp(fl FieldLevel) bool {
+	return fl.Field.Kind() == reflect.String && v.compile(fl.Param).MatchString(fl.Field.String())
+}
+
+func hasValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return false
+	case reflect.Pointer, reflect.Interface:
+		return !v.IsNil()
+	case reflect.Slice, reflect.Map:
+		return v.Len() > 0
+	default:
+		return !v.IsZero()
+	}
+}
+
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+func interfaceOf(v reflect.Value) any {
+	if v.IsValid() && v.CanInterface() {
+		return v.Interface()
+	}
+	return nil
+}
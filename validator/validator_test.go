@@ -0,0 +1,97 @@
+package validator
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type address struct {
+	City string `validate:"required"`
+	Zip  string `validate:"omitempty,regexp=^[0-9]{6}$"`
+}
+
+type user struct {
+	Name     string         `validate:"required,min=2,max=5"`
+	Age      int            `validate:"min=18,max=120"`
+	Email    string         `validate:"omitempty,email"`
+	Role     string         `validate:"oneof=admin user"`
+	Password string         `validate:"required"`
+	Confirm  string         `validate:"eqfield=Password"`
+	Code     string         `validate:"len=4"`
+	Tags     []string       `validate:"max=3,dive,required"`
+	Scores   map[string]int `validate:"dive,min=0"`
+	Admin    *bool          `validate:"required"`
+	Address  address
+	Others   []*address        `validate:"dive"`
+	Extra    map[string]string `validate:"-"`
+}
+
+func TestStruct(t *testing.T) {
+	admin := false
+	valid := user{
+		Name: "tom", Age: 20, Role: "user", Password: "x", Confirm: "x", Code: "abcd",
+		Tags: []string{"a"}, Scores: map[string]int{"go": 1}, Admin: &admin,
+		Address: address{City: "sh", Zip: "200000"},
+	}
+	if err := Struct(&valid); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := user{
+		Name: "t", Age: 10, Email: "tom", Role: "root", Confirm: "y", Code: "abc",
+		Tags: []string{"a", ""}, Scores: map[string]int{"go": -1},
+		Address: address{Zip: "abc"}, Others: []*address{{City: "bj"}, {}},
+	}
+	err := Struct(invalid)
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("err = %v", err)
+	}
+	got := map[string]string{}
+	for _, e := range errs {
+		got[e.Field] = e.Tag
+	}
+	want := map[string]string{
+		"Name": "min", "Age": "min", "Email": "email", "Role": "oneof", "Password": "required",
+		"Confirm": "eqfield", "Code": "len", "Tags[1]": "required", "Scores[go]": "min", "Admin": "required",
+		"Address.City": "required", "Address.Zip": "regexp", "Others[1].City": "required",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	data, _ := json.Marshal(errs[0])
+	if string(data) != `{"field":"Name","tag":"min","param":"2","message":"Name must be at least 2"}` {
+		t.Fatalf("json = %s", data)
+	}
+}
+
+func TestRegisterValidation(t *testing.T) {
+	v := New()
+	v.RegisterValidation("prefix", func(fl FieldLevel) bool {
+		return strings.HasPrefix(fl.Field.String(), fl.Param)
+	})
+	var obj struct {
+		ID string `validate:"prefix=u_"`
+	}
+	obj.ID = "u_1"
+	if err := v.Struct(obj); err != nil {
+		t.Fatal(err)
+	}
+	obj.ID = "1"
+	if err := v.Struct(obj); err == nil || err.Error() != "ID failed on the prefix=u_ rule" {
+		t.Fatalf("err = %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for undefined validation")
+		}
+	}()
+	v.Struct(struct {
+		Name string `validate:"unknown"`
+	}{})
+}
@@ -0,0 +1,24 @@
+package go_framework
+
+import (
+	"net/http"
+)
+
+// WebSocket 注册 GET 路由，握手成功后通过 ctx.WS 读写消息，handler 返回时关闭连接，
+// 握手失败时已经返回错误响应，不会调用 handler。握手配置见 Engine.WebSocketUpgrader
+func (r *RouterGroup) WebSocket(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) *Route {
+	upgrader := &r.router.engine.WebSocketUpgrader
+	return r.Get(name, func(ctx *Context) {
+		ctx.W.WriteHeader(http.StatusSwitchingProtocols)
+		conn, err := upgrader.Upgrade(ctx.W, ctx.R, nil)
+		if err != nil {
+			ctx.StatusCode = ctx.W.Status()
+			ctx.Abort()
+			return
+		}
+		ctx.StatusCode = http.StatusSwitchingProtocols
+		ctx.WS = conn
+		defer conn.Close()
+		handlerFunc(ctx)
+	}, middlewareFunc...)
+}
@@ -0,0 +1,494 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 消息类型，与帧的 opcode 相同
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+)
+
+const continuationFrame = 0
+
+// 关闭状态码，见 RFC 6455 7.4.1
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseInternalServerErr       = 1011
+	CloseTryAgainLater           = 1013
+)
+
+const (
+	finalBit = 1 << 7
+	rsvBits  = 7 << 4
+	maskBit  = 1 << 7
+
+	maxControlPayload = 125
+)
+
+// DefaultReadLimit 没有设置读取限制时单条消息的最大字节数
+const DefaultReadLimit = 32 << 20
+
+var (
+	// ErrReadLimit 消息超过 SetReadLimit 设置的大小
+	ErrReadLimit = errors.New("websocket: read limit exceeded")
+	// ErrCloseSent 已经发送过关闭帧，不能再写入
+	ErrCloseSent = errors.New("websocket: close sent")
+)
+
+// CloseError 对端关闭连接或连接异常断开
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Text)
+}
+
+// IsCloseError err 是否为 codes 中任一状态码的 CloseError
+func IsCloseError(err error, codes ...int) bool {
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) {
+		return false
+	}
+	for _, code := range codes {
+		if closeErr.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Conn WebSocket 连接，读和写可以分别在两个 goroutine 中进行，
+// 多个 goroutine 同时写入时按消息串行，不会交错
+type Conn struct {
+	conn     net.Conn
+	br       *bufio.Reader
+	isServer bool
+
+	subprotocol string
+	readLimit   int64
+	readErr     error
+
+	// messageMu 保证一条消息的所有帧连续写入，控制帧可以插在分片之间
+	messageMu sync.Mutex
+	// frameMu 保证单个帧完整写入
+	frameMu   sync.Mutex
+	closeSent bool
+
+	handlerMu   sync.Mutex
+	pingHandler func(data []byte) error
+	pongHandler func(data []byte) error
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, isServer bool) *Conn {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+	c := &Conn{conn: conn, br: br, isServer: isServer, readLimit: DefaultReadLimit}
+	c.pingHandler = c.replyPong
+	c.pongHandler = func([]byte) error { return nil }
+	return c
+}
+
+func (c *Conn) replyPong(data []byte) error {
+	err := c.WriteControl(PongMessage, data)
+	if errors.Is(err, ErrCloseSent) {
+		return nil
+	}
+	return err
+}
+
+// Subprotocol 握手时协商出的子协议
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// NetConn 底层连接
+func (c *Conn) NetConn() net.Conn {
+	return c.conn
+}
+
+// SetReadLimit 单条消息的最大字节数，超过时发送 1009 关闭帧并返回 ErrReadLimit，小于等于 0 时使用 DefaultReadLimit
+func (c *Conn) SetReadLimit(limit int64) {
+	if limit <= 0 {
+		limit = DefaultReadLimit
+	}
+	c.readLimit = limit
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// SetPingHandler 收到 ping 时调用，默认回复相同内容的 pong，在 ReadMessage 所在的 goroutine 中执行
+func (c *Conn) SetPingHandler(h func(data []byte) error) {
+	c.handlerMu.Lock()
+	defer c.handlerMu.Unlock()
+	if h == nil {
+		h = c.replyPong
+	}
+	c.pingHandler = h
+}
+
+// SetPongHandler 收到 pong 时调用，常用于延长读超时
+func (c *Conn) SetPongHandler(h func(data []byte) error) {
+	c.handlerMu.Lock()
+	defer c.handlerMu.Unlock()
+	if h == nil {
+		h = func([]byte) error { return nil }
+	}
+	c.pongHandler = h
+}
+
+// ReadMessage 读取一条完整的消息，分片消息会被合并，期间收到的控制帧交给对应的 handler 处理，
+// 收到关闭帧时回复关闭帧并返回 *CloseError，出错后后续调用返回同一个错误
+func (c *Conn) ReadMessage() (messageType int, p []byte, err error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+	messageType, p, err = c.readMessage()
+	if err != nil {
+		c.readErr = err
+	}
+	return
+}
+
+// ReadJSON 读取一条消息并解码为 JSON
+func (c *Conn) ReadJSON(v any) error {
+	_, p, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(p, v)
+}
+
+func (c *Conn) readMessage() (int, []byte, error) {
+	messageType := 0
+	var message []byte
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch opcode {
+		case CloseMessage, PingMessage, PongMessage:
+			if err := c.handleControl(opcode, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "expected continuation frame")
+			}
+			messageType = opcode
+		default:
+			return 0, nil, c.fail(CloseProtocolError, fmt.Sprintf("unknown opcode %d", opcode))
+		}
+		message = append(message, payload...)
+		if int64(len(message)) > c.readLimit {
+			return 0, nil, c.failReadLimit()
+		}
+		if fin {
+			if messageType == TextMessage && !utf8.Valid(message) {
+				return 0, nil, c.fail(CloseInvalidFramePayloadData, "invalid utf8 payload")
+			}
+			return messageType, message, nil
+		}
+	}
+}
+
+// readFrame 读取一个帧并去掉掩码，分片消息累计的长度在 readMessage 中检查
+func (c *Conn) readFrame() (fin bool, opcode int, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(c.br, header[:]); err != nil {
+		return false, 0, nil, c.readFailed(err)
+	}
+	fin = header[0]&finalBit != 0
+	opcode = int(header[0] & 0x0f)
+	if header[0]&rsvBits != 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "unexpected reserved bits")
+	}
+	masked := header[1]&maskBit != 0
+	if masked != c.isServer {
+		return false, 0, nil, c.fail(CloseProtocolError, "bad frame masking")
+	}
+	length := int64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, c.readFailed(err)
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, c.readFailed(err)
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+		if length < 0 {
+			return false, 0, nil, c.fail(CloseProtocolError, "invalid payload length")
+		}
+	}
+	if opcode >= CloseMessage && (!fin || length > maxControlPayload) {
+		return false, 0, nil, c.fail(CloseProtocolError, "invalid control frame")
+	}
+	if length > c.readLimit {
+		return false, 0, nil, c.failReadLimit()
+	}
+	var maskKey [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.br, maskKey[:]); err != nil {
+			return false, 0, nil, c.readFailed(err)
+		}
+	}
+	// 按实际收到的数据扩容，不按对端声明的长度一次性分配
+	var buf bytes.Buffer
+	if _, err = io.CopyN(&buf, c.br, length); err != nil {
+		return false, 0, nil, c.readFailed(err)
+	}
+	payload = buf.Bytes()
+	if masked {
+		maskBytes(maskKey, payload)
+	}
+	return fin, opcode, payload, nil
+}
+
+func (c *Conn) handleControl(opcode int, payload []byte) error {
+	c.handlerMu.Lock()
+	ping, pong := c.pingHandler, c.pongHandler
+	c.handlerMu.Unlock()
+	switch opcode {
+	case PingMessage:
+		return ping(payload)
+	case PongMessage:
+		return pong(payload)
+	}
+	closeErr := &CloseError{Code: CloseNoStatusReceived}
+	if len(payload) >= 2 {
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Text = string(payload[2:])
+		if !utf8.ValidString(closeErr.Text) {
+			return c.fail(CloseProtocolError, "invalid utf8 close reason")
+		}
+	} else if len(payload) == 1 {
+		return c.fail(CloseProtocolError, "invalid close payload")
+	}
+	reply := closeErr.Code
+	if reply == CloseNoStatusReceived {
+		reply = CloseNormalClosure
+	}
+	c.WriteClose(reply, "")
+	return closeErr
+}
+
+// fail 协议错误时发送关闭帧
+func (c *Conn) fail(code int, text string) error {
+	c.WriteClose(code, text)
+	return &CloseError{Code: code, Text: text}
+}
+
+func (c *Conn) failReadLimit() error {
+	c.WriteClose(CloseMessageTooBig, "")
+	return ErrReadLimit
+}
+
+// readFailed 连接没有收到关闭帧就断开时返回 1006
+func (c *Conn) readFailed(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return &CloseError{Code: CloseAbnormalClosure, Text: err.Error()}
+	}
+	return err
+}
+
+// WriteMessage 写入一条不分片的消息，messageType 为控制帧时等同于 WriteControl
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case TextMessage, BinaryMessage:
+	case CloseMessage, PingMessage, PongMessage:
+		return c.WriteControl(messageType, data)
+	default:
+		return fmt.Errorf("websocket: unknown message type %d", messageType)
+	}
+	c.messageMu.Lock()
+	defer c.messageMu.Unlock()
+	return c.writeFrame(true, messageType, data)
+}
+
+// WriteJSON 把 v 编码为 JSON 后作为文本消息写入
+func (c *Conn) WriteJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(TextMessage, data)
+}
+
+// WriteControl 写入 ping、pong 或关闭帧，不需要等待正在写入的分片消息
+func (c *Conn) WriteControl(messageType int, data []byte) error {
+	if messageType != CloseMessage && messageType != PingMessage && messageType != PongMessage {
+		return fmt.Errorf("websocket: %d is not a control message", messageType)
+	}
+	if len(data) > maxControlPayload {
+		return errors.New("websocket: control frame payload too large")
+	}
+	return c.writeFrame(true, messageType, data)
+}
+
+// WriteClose 发送关闭帧，之后不能再写入消息
+func (c *Conn) WriteClose(code int, text string) error {
+	var data []byte
+	if code != CloseNoStatusReceived {
+		data = make([]byte, 2, 2+len(text))
+		binary.BigEndian.PutUint16(data, uint16(code))
+		data = append(data, text...)
+	}
+	if len(data) > maxControlPayload {
+		data = data[:maxControlPayload]
+	}
+	return c.writeFrame(true, CloseMessage, data)
+}
+
+// NextWriter 以分片的方式写入一条消息，每次 Write 写入一个帧，Close 时写入结束帧，
+// Close 之前其他 goroutine 的消息会等待
+func (c *Conn) NextWriter(messageType int) (io.WriteCloser, error) {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return nil, fmt.Errorf("websocket: %d is not a data message", messageType)
+	}
+	c.messageMu.Lock()
+	return &messageWriter{c: c, opcode: messageType}, nil
+}
+
+// Close 发送正常关闭帧后关闭底层连接
+func (c *Conn) Close() error {
+	c.WriteClose(CloseNormalClosure, "")
+	return c.conn.Close()
+}
+
+func (c *Conn) writeFrame(fin bool, opcode int, payload []byte) error {
+	c.frameMu.Lock()
+	defer c.frameMu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	if opcode == CloseMessage {
+		c.closeSent = true
+	}
+	frame := make([]byte, 0, 14+len(payload))
+	b0 := byte(opcode)
+	if fin {
+		b0 |= finalBit
+	}
+	frame = append(frame, b0)
+	var b1 byte
+	if !c.isServer {
+		b1 = maskBit
+	}
+	switch length := len(payload); {
+	case length <= 125:
+		frame = append(frame, b1|byte(length))
+	case length <= 0xffff:
+		frame = append(frame, b1|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, b1|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+	if c.isServer {
+		frame = append(frame, payload...)
+	} else {
+		var maskKey [4]byte
+		binary.BigEndian.PutUint32(maskKey[:], rand.Uint32())
+		frame = append(frame, maskKey[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		maskBytes(maskKey, frame[start:])
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+func maskBytes(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i&3]
+	}
+}
+
+type messageWriter struct {
+	c       *Conn
+	opcode  int
+	started bool
+	closed  bool
+}
+
+func (w *messageWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("websocket: write to closed writer")
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := w.c.writeFrame(false, w.frameOpcode(), p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *messageWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer w.c.messageMu.Unlock()
+	return w.c.writeFrame(true, w.frameOpcode(), nil)
+}
+
+// frameOpcode 第一帧使用消息类型，后面的帧使用 continuation
+func (w *messageWriter) frameOpcode() int {
+	if w.started {
+		return continuationFrame
+	}
+	w.started = true
+	return w.opcode
+}
@@ -0,0 +1,315 @@
+package websocket
+
+import (
+	"errors"
+	"github.com/JUYAFEI/go-framework/pool"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultQueueSize 每个连接发送队列的默认长度
+	DefaultQueueSize = 256
+	// fanoutBatch 广播时每个任务负责的连接数
+	fanoutBatch = 64
+	// closeTimeout 关闭连接时写入关闭帧的超时时间
+	closeTimeout = time.Second
+)
+
+var (
+	// ErrQueueFull 发送队列已满，对端读取太慢
+	ErrQueueFull = errors.New("websocket: send queue full")
+	// ErrClientClosed 连接已经从 Hub 中移除
+	ErrClientClosed = errors.New("websocket: client closed")
+)
+
+type message struct {
+	messageType int
+	data        []byte
+}
+
+// Hub 管理连接和房间，向房间或所有连接广播消息。
+// 每个连接有独立的发送队列和写协程，广播只把消息放入队列，
+// 队列已满的连接会以 1013 关闭，不会拖慢其他连接
+type Hub struct {
+	pool      *pool.Pool
+	queueSize int
+
+	mu      sync.RWMutex
+	clients map[*Client]struct{}
+	rooms   map[string]map[*Client]struct{}
+}
+
+// Client Hub 中的一个连接
+type Client struct {
+	hub   *Hub
+	conn  *Conn
+	send  chan message
+	done  chan struct{}
+	once  sync.Once
+	rooms map[string]struct{}
+}
+
+// NewHub p 用于分发广播，为 nil 时在调用 Broadcast 的协程中分发，
+// queueSize 小于等于 0 时使用 DefaultQueueSize
+func NewHub(p *pool.Pool, queueSize int) *Hub {
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	return &Hub{
+		pool:      p,
+		queueSize: queueSize,
+		clients:   make(map[*Client]struct{}),
+		rooms:     make(map[string]map[*Client]struct{}),
+	}
+}
+
+// Register 把连接加入 Hub 并启动写协程
+func (h *Hub) Register(conn *Conn) *Client {
+	c := &Client{
+		hub:   h,
+		conn:  conn,
+		send:  make(chan message, h.queueSize),
+		done:  make(chan struct{}),
+		rooms: make(map[string]struct{}),
+	}
+	h.mu.Lock()
+	h.clients[c] = struct{}{}
+	h.mu.Unlock()
+	go c.writeLoop()
+	return c
+}
+
+// Broadcast 向所有连接广播
+func (h *Hub) Broadcast(messageType int, data []byte) {
+	h.mu.RLock()
+	clients := make([]*Client, 0, len(h.clients))
+	for c := range h.clients {
+		clients = append(clients, c)
+	}
+	h.mu.RUnlock()
+	h.fanout(clients, message{messageType: messageType, data: data})
+}
+
+// BroadcastRoom 向房间中的连接广播
+func (h *Hub) BroadcastRoom(room string, messageType int, data []byte) {
+	h.mu.RLock()
+	members := h.rooms[room]
+	clients := make([]*Client, 0, len(members))
+	for c := range members {
+		clients = append(clients, c)
+	}
+	h.mu.RUnlock()
+	h.fanout(clients, message{messageType: messageType, data: data})
+}
+
+// fanout 按批提交到协程池，全部放入队列后返回，同一协程的多次广播保持顺序
+func (h *Hub) fanout(clients []*Client, m message) {
+	if h.pool == nil || len(clients) <= fanoutBatch {
+		deliver(clients, m)
+		return
+	}
+	var wg sync.WaitGroup
+	for start := 0; start < len(clients); start += fanoutBatch {
+		end := start + fanoutBatch
+		if end > len(clients) {
+			end = len(clients)
+		}
+		batch := clients[start:end]
+		wg.Add(1)
+		err := h.pool.Submit(func() {
+			defer wg.Done()
+			deliver(batch, m)
+		})
+		if err != nil {
+			wg.Done()
+			deliver(batch, m)
+		}
+	}
+	wg.Wait()
+}
+
+func deliver(clients []*Client, m message) {
+	for _, c := range clients {
+		if err := c.enqueue(m); errors.Is(err, ErrQueueFull) {
+			go c.CloseWith(CloseTryAgainLater, "send queue full")
+		}
+	}
+}
+
+// Count 当前连接数
+func (h *Hub) Count() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+// RoomCount 房间中的连接数
+func (h *Hub) RoomCount(room string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.rooms[room])
+}
+
+// Rooms 所有房间及其连接数，不包含已经没有连接的房间
+func (h *Hub) Rooms() map[string]int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	rooms := make(map[string]int, len(h.rooms))
+	for room, members := range h.rooms {
+		rooms[room] = len(members)
+	}
+	return rooms
+}
+
+// Close 关闭所有连接
+func (h *Hub) Close() {
+	h.mu.RLock()
+	clients := make([]*Client, 0, len(h.clients))
+	for c := range h.clients {
+		clients = append(clients, c)
+	}
+	h.mu.RUnlock()
+	for _, c := range clients {
+		c.CloseWith(CloseGoingAway, "")
+	}
+}
+
+func (h *Hub) remove(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.clients, c)
+	for room := range c.rooms {
+		h.leave(c, room)
+	}
+}
+
+func (h *Hub) leave(c *Client, room string) {
+	delete(c.rooms, room)
+	if members, ok := h.rooms[room]; ok {
+		delete(members, c)
+		if len(members) == 0 {
+			delete(h.rooms, room)
+		}
+	}
+}
+
+// Conn 底层的 WebSocket 连接，读取消息使用 Listen 或 Conn().ReadMessage
+func (c *Client) Conn() *Conn {
+	return c.conn
+}
+
+// Join 加入房间，连接关闭后调用不生效
+func (c *Client) Join(room string) {
+	h := c.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[c]; !ok {
+		return
+	}
+	members, ok := h.rooms[room]
+	if !ok {
+		members = make(map[*Client]struct{})
+		h.rooms[room] = members
+	}
+	members[c] = struct{}{}
+	c.rooms[room] = struct{}{}
+}
+
+// Leave 离开房间
+func (c *Client) Leave(room string) {
+	h := c.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.leave(c, room)
+}
+
+// Rooms 已加入的房间，按名称排序
+func (c *Client) Rooms() []string {
+	c.hub.mu.RLock()
+	rooms := make([]string, 0, len(c.rooms))
+	for room := range c.rooms {
+		rooms = append(rooms, room)
+	}
+	c.hub.mu.RUnlock()
+	sort.Strings(rooms)
+	return rooms
+}
+
+// Send 把消息放入发送队列，不会阻塞，队列已满时返回 ErrQueueFull
+func (c *Client) Send(messageType int, data []byte) error {
+	return c.enqueue(message{messageType: messageType, data: data})
+}
+
+func (c *Client) enqueue(m message) error {
+	select {
+	case <-c.done:
+		return ErrClientClosed
+	default:
+	}
+	select {
+	case c.send <- m:
+		return nil
+	case <-c.done:
+		return ErrClientClosed
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Listen 循环读取消息并交给 handler，连接出错或关闭时从 Hub 中移除并返回读取的错误
+func (c *Client) Listen(handler func(messageType int, data []byte)) error {
+	defer c.Close()
+	for {
+		messageType, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		handler(messageType, data)
+	}
+}
+
+// Close 以 1000 关闭连接并从 Hub 中移除，队列中未发送的消息会被丢弃
+func (c *Client) Close() {
+	c.CloseWith(CloseNormalClosure, "")
+}
+
+// CloseWith 以指定的状态码关闭连接
+func (c *Client) CloseWith(code int, text string) {
+	c.shutdown(func() {
+		c.conn.SetWriteDeadline(time.Now().Add(closeTimeout))
+		c.conn.WriteClose(code, text)
+	})
+}
+
+// shutdown 从 Hub 中移除并关闭底层连接，beforeClose 用于发送关闭帧
+func (c *Client) shutdown(beforeClose func()) {
+	c.once.Do(func() {
+		c.hub.remove(c)
+		close(c.done)
+		if beforeClose != nil {
+			beforeClose()
+		}
+		c.conn.NetConn().Close()
+	})
+}
+
+// Done 连接关闭后返回的 channel 会被关闭
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *Client) writeLoop() {
+	for {
+		select {
+		case m := <-c.send:
+			if err := c.conn.WriteMessage(m.messageType, m.data); err != nil {
+				c.shutdown(nil)
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
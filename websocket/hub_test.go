@@ -0,0 +1,99 @@
+package websocket
+
+import (
+	"errors"
+	"github.com/JUYAFEI/go-framework/pool"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHub(t *testing.T) {
+	p, _ := pool.NewPool(4)
+	defer p.Release()
+	hub := NewHub(p, 0)
+	joined := make(chan *Client, 3)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := (&Upgrader{}).Upgrade(w, req, nil)
+		if err != nil {
+			return
+		}
+		client := hub.Register(conn)
+		client.Join(req.URL.Query().Get("room"))
+		joined <- client
+		client.Listen(func(messageType int, data []byte) {
+			hub.BroadcastRoom(req.URL.Query().Get("room"), messageType, data)
+		})
+	}))
+	defer server.Close()
+
+	alice, _ := dial(t, server.URL+"/?room=go", nil)
+	bob, _ := dial(t, server.URL+"/?room=go", nil)
+	carol, _ := dial(t, server.URL+"/?room=rust", nil)
+	defer alice.Close()
+	defer carol.Close()
+	for i := 0; i < 3; i++ {
+		<-joined
+	}
+	if hub.Count() != 3 || hub.RoomCount("go") != 2 || hub.Rooms()["rust"] != 1 {
+		t.Fatalf("count = %d, rooms = %v", hub.Count(), hub.Rooms())
+	}
+
+	alice.WriteMessage(TextMessage, []byte("hello go"))
+	for _, conn := range []*Conn{alice, bob} {
+		if _, p, err := conn.ReadMessage(); err != nil || string(p) != "hello go" {
+			t.Fatalf("p = %q, err = %v", p, err)
+		}
+	}
+	hub.Broadcast(TextMessage, []byte("all"))
+	for _, conn := range []*Conn{alice, bob, carol} {
+		if _, p, err := conn.ReadMessage(); err != nil || string(p) != "all" {
+			t.Fatalf("p = %q, err = %v", p, err)
+		}
+	}
+
+	bob.Close()
+	deadline := time.Now().Add(time.Second)
+	for hub.RoomCount("go") != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if hub.Count() != 2 || hub.RoomCount("go") != 1 {
+		t.Fatalf("count = %d, rooms = %v", hub.Count(), hub.Rooms())
+	}
+}
+
+func TestHubBackpressure(t *testing.T) {
+	hub := NewHub(nil, 1)
+	server, client := net.Pipe()
+	defer client.Close()
+	c := hub.Register(newConn(server, nil, true))
+	c.Join("room")
+
+	// net.Pipe 没有缓冲，对端不读取时第一条消息阻塞在写协程中
+	c.Send(TextMessage, []byte("1"))
+	deadline := time.Now().Add(time.Second)
+	for len(c.send) != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if err := c.Send(TextMessage, []byte("2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Send(TextMessage, []byte("3")); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("err = %v", err)
+	}
+
+	hub.BroadcastRoom("room", TextMessage, []byte("4"))
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatal("slow client not closed")
+	}
+	if hub.Count() != 0 || len(hub.Rooms()) != 0 {
+		t.Fatalf("count = %d, rooms = %v", hub.Count(), hub.Rooms())
+	}
+	if err := c.Send(TextMessage, []byte("5")); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("err = %v", err)
+	}
+}
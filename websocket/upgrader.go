@@ -0,0 +1,173 @@
+package websocket
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// acceptGUID 计算 Sec-WebSocket-Accept 使用的固定值，见 RFC 6455 1.3
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// HandshakeError 握手失败，Upgrade 已经向客户端返回了错误响应
+type HandshakeError struct {
+	Status  int
+	Message string
+}
+
+func (e *HandshakeError) Error() string {
+	return "websocket: " + e.Message
+}
+
+// Upgrader 把 HTTP 请求升级为 WebSocket 连接，零值可以直接使用
+type Upgrader struct {
+	// HandshakeTimeout 写入握手响应的超时时间，0 表示不限制
+	HandshakeTimeout time.Duration
+	// ReadLimit 新连接的单条消息最大字节数，0 表示使用 DefaultReadLimit
+	ReadLimit int64
+	// Subprotocols 服务端支持的子协议，按优先级排列
+	Subprotocols []string
+	// CheckOrigin 返回 false 时拒绝握手并返回 403，为 nil 时只允许没有 Origin 或 Origin 与 Host 相同的请求
+	CheckOrigin func(req *http.Request) bool
+}
+
+// Upgrade 完成握手并接管连接，header 中的响应头会随握手响应一起发送
+func (u *Upgrader) Upgrade(w http.ResponseWriter, req *http.Request, header http.Header) (*Conn, error) {
+	if req.Method != http.MethodGet {
+		return u.fail(w, http.StatusMethodNotAllowed, "request method is not GET")
+	}
+	if !headerContainsToken(req.Header, "Connection", "upgrade") {
+		return u.fail(w, http.StatusBadRequest, "'upgrade' token not found in 'Connection' header")
+	}
+	if !headerContainsToken(req.Header, "Upgrade", "websocket") {
+		return u.fail(w, http.StatusBadRequest, "'websocket' token not found in 'Upgrade' header")
+	}
+	if req.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return u.fail(w, http.StatusUpgradeRequired, "unsupported version")
+	}
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(req) {
+		return u.fail(w, http.StatusForbidden, "request origin not allowed")
+	}
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return u.fail(w, http.StatusBadRequest, "invalid 'Sec-WebSocket-Key' header")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return u.fail(w, http.StatusInternalServerError, "response does not implement http.Hijacker")
+	}
+	subprotocol := u.selectSubprotocol(req)
+
+	netConn, brw, err := hijacker.Hijack()
+	if err != nil {
+		return u.fail(w, http.StatusInternalServerError, err.Error())
+	}
+	// 清除 http.Server 设置的读写超时
+	netConn.SetDeadline(time.Time{})
+
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: ")
+	b.WriteString(acceptKey(key))
+	b.WriteString("\r\n")
+	if subprotocol != "" {
+		b.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	for k, values := range header {
+		if k == "Sec-Websocket-Protocol" {
+			continue
+		}
+		for _, v := range values {
+			b.WriteString(k + ": " + strings.NewReplacer("\r", "", "\n", "").Replace(v) + "\r\n")
+		}
+	}
+	b.WriteString("\r\n")
+	if u.HandshakeTimeout > 0 {
+		netConn.SetWriteDeadline(time.Now().Add(u.HandshakeTimeout))
+	}
+	if _, err := netConn.Write([]byte(b.String())); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	if u.HandshakeTimeout > 0 {
+		netConn.SetWriteDeadline(time.Time{})
+	}
+
+	conn := newConn(netConn, brw.Reader, true)
+	conn.subprotocol = subprotocol
+	conn.SetReadLimit(u.ReadLimit)
+	return conn, nil
+}
+
+func (u *Upgrader) fail(w http.ResponseWriter, status int, message string) (*Conn, error) {
+	http.Error(w, http.StatusText(status), status)
+	return nil, &HandshakeError{Status: status, Message: message}
+}
+
+// selectSubprotocol 按服务端的优先级选择客户端也支持的子协议
+func (u *Upgrader) selectSubprotocol(req *http.Request) string {
+	requested := Subprotocols(req)
+	for _, server := range u.Subprotocols {
+		for _, client := range requested {
+			if client == server {
+				return server
+			}
+		}
+	}
+	return ""
+}
+
+// Subprotocols 客户端在 Sec-WebSocket-Protocol 中请求的子协议
+func Subprotocols(req *http.Request) []string {
+	var protocols []string
+	for _, value := range req.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(value, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				protocols = append(protocols, p)
+			}
+		}
+	}
+	return protocols
+}
+
+// IsWebSocketUpgrade 请求是否为 WebSocket 握手
+func IsWebSocketUpgrade(req *http.Request) bool {
+	return headerContainsToken(req.Header, "Connection", "upgrade") &&
+		headerContainsToken(req.Header, "Upgrade", "websocket")
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func headerContainsToken(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sameOrigin(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, req.Host)
+}
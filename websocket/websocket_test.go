@@ -0,0 +1,177 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+// dial 手动完成握手，返回客户端一侧的连接
+func dial(t *testing.T, rawURL string, header http.Header) (*Conn, *http.Response) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, rawURL, nil)
+	netConn, err := net.Dial("tcp", req.URL.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if err := req.Write(netConn); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(netConn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		netConn.Close()
+		return nil, resp
+	}
+	return newConn(netConn, br, false), resp
+}
+
+func echoServer(u *Upgrader) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := u.Upgrade(w, req, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, p, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, p); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func TestEcho(t *testing.T) {
+	server := echoServer(&Upgrader{Subprotocols: []string{"chat"}})
+	defer server.Close()
+	conn, resp := dial(t, server.URL, http.Header{"Sec-Websocket-Protocol": {"json, chat"}})
+	defer conn.Close()
+	if resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" || resp.Header.Get("Sec-WebSocket-Protocol") != "chat" {
+		t.Fatalf("header = %v", resp.Header)
+	}
+
+	if err := conn.WriteMessage(TextMessage, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if messageType, p, err := conn.ReadMessage(); err != nil || messageType != TextMessage || string(p) != "hello" {
+		t.Fatalf("type = %d, p = %q, err = %v", messageType, p, err)
+	}
+
+	big := bytes.Repeat([]byte("x"), 70000)
+	w, _ := conn.NextWriter(BinaryMessage)
+	w.Write(big[:100])
+	conn.WriteControl(PingMessage, []byte("ping"))
+	w.Write(big[100:])
+	w.Close()
+	pong := make(chan string, 1)
+	conn.SetPongHandler(func(data []byte) error {
+		pong <- string(data)
+		return nil
+	})
+	if messageType, p, err := conn.ReadMessage(); err != nil || messageType != BinaryMessage || !bytes.Equal(p, big) {
+		t.Fatalf("type = %d, len = %d, err = %v", messageType, len(p), err)
+	}
+	if got := <-pong; got != "ping" {
+		t.Fatalf("pong = %q", got)
+	}
+
+	conn.WriteClose(CloseGoingAway, "bye")
+	if _, _, err := conn.ReadMessage(); !IsCloseError(err, CloseGoingAway) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestReadLimitAndInvalidUTF8(t *testing.T) {
+	server := echoServer(&Upgrader{ReadLimit: 8})
+	defer server.Close()
+
+	conn, _ := dial(t, server.URL, nil)
+	conn.WriteMessage(TextMessage, []byte("0123456789"))
+	if _, _, err := conn.ReadMessage(); !IsCloseError(err, CloseMessageTooBig) {
+		t.Fatalf("err = %v", err)
+	}
+	conn.Close()
+
+	conn, _ = dial(t, server.URL, nil)
+	conn.WriteMessage(TextMessage, []byte{0xff, 0xfe})
+	if _, _, err := conn.ReadMessage(); !IsCloseError(err, CloseInvalidFramePayloadData) {
+		t.Fatalf("err = %v", err)
+	}
+	conn.Close()
+}
+
+func TestOversizedLength(t *testing.T) {
+	server := echoServer(&Upgrader{})
+	defer server.Close()
+
+	// 声明 1 TiB 的长度，超过默认的读取限制，不发送数据
+	conn, _ := dial(t, server.URL, nil)
+	frame := []byte{finalBit | BinaryMessage, maskBit | 127}
+	frame = binary.BigEndian.AppendUint64(frame, 1<<40)
+	frame = append(frame, 1, 2, 3, 4)
+	conn.NetConn().Write(frame)
+	if _, _, err := conn.ReadMessage(); !IsCloseError(err, CloseMessageTooBig) {
+		t.Fatalf("err = %v", err)
+	}
+	conn.Close()
+
+	// 声明的长度在限制内但对端只发送了少量数据，不应按声明的长度分配
+	s, c := net.Pipe()
+	defer s.Close()
+	go func() {
+		frame := []byte{finalBit | BinaryMessage, maskBit | 127}
+		frame = binary.BigEndian.AppendUint64(frame, DefaultReadLimit)
+		frame = append(frame, 1, 2, 3, 4)
+		c.Write(append(frame, "0123456789"...))
+		c.Close()
+	}()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, _, err := newConn(s, nil, true).ReadMessage()
+	runtime.ReadMemStats(&after)
+	if !IsCloseError(err, CloseAbnormalClosure) {
+		t.Fatalf("err = %v", err)
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 1<<20 {
+		t.Fatalf("allocated %d bytes", alloc)
+	}
+}
+
+func TestHandshakeRejected(t *testing.T) {
+	var handshakeErr error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, handshakeErr = (&Upgrader{}).Upgrade(w, req, nil)
+	}))
+	defer server.Close()
+
+	_, resp := dial(t, server.URL, http.Header{"Origin": {"http://evil.example.com"}})
+	var err *HandshakeError
+	if resp.StatusCode != http.StatusForbidden || !errors.As(handshakeErr, &err) {
+		t.Fatalf("status = %d, err = %v", resp.StatusCode, handshakeErr)
+	}
+
+	resp, _ = http.Get(server.URL)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+}